@@ -3,7 +3,11 @@ package main
 import (
 	"flag"
 	"fmt"
+	"io"
 	"log"
+	"os"
+	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
@@ -12,6 +16,7 @@ import (
 	"github.com/Nextasy01/grpc-file-service/service"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 const (
@@ -29,9 +34,39 @@ func authMethods() map[string]bool {
 	const fileServicePath = "/file.service.FileService/"
 
 	return map[string]bool{
-		fmt.Sprintf("%sUpload", fileServicePath):   true,
-		fmt.Sprintf("%sDownload", fileServicePath): true,
-		fmt.Sprintf("%sList", fileServicePath):     true,
+		fmt.Sprintf("%sUpload", fileServicePath):                  true,
+		fmt.Sprintf("%sDownload", fileServicePath):                true,
+		fmt.Sprintf("%sList", fileServicePath):                    true,
+		fmt.Sprintf("%sDelete", fileServicePath):                  true,
+		fmt.Sprintf("%sCreateUploadSession", fileServicePath):     true,
+		fmt.Sprintf("%sGetUploadSession", fileServicePath):        true,
+		fmt.Sprintf("%sResumeUpload", fileServicePath):            true,
+		fmt.Sprintf("%sGetUsage", fileServicePath):                true,
+		fmt.Sprintf("%sListVersions", fileServicePath):            true,
+		fmt.Sprintf("%sRestoreVersion", fileServicePath):          true,
+		fmt.Sprintf("%sPruneVersions", fileServicePath):           true,
+		fmt.Sprintf("%sListTrash", fileServicePath):               true,
+		fmt.Sprintf("%sRestoreFromTrash", fileServicePath):        true,
+		fmt.Sprintf("%sEmptyTrash", fileServicePath):              true,
+		fmt.Sprintf("%sInitiateMultipartUpload", fileServicePath): true,
+		fmt.Sprintf("%sUploadPart", fileServicePath):              true,
+		fmt.Sprintf("%sCompleteMultipartUpload", fileServicePath): true,
+		fmt.Sprintf("%sAbortMultipartUpload", fileServicePath):    true,
+		fmt.Sprintf("%sDownloadArchive", fileServicePath):         true,
+		fmt.Sprintf("%sCreateFolder", fileServicePath):            true,
+		fmt.Sprintf("%sMove", fileServicePath):                    true,
+		fmt.Sprintf("%sDeleteFolder", fileServicePath):            true,
+		fmt.Sprintf("%sUpdateMetadata", fileServicePath):          true,
+		fmt.Sprintf("%sSearch", fileServicePath):                  true,
+		fmt.Sprintf("%sWatch", fileServicePath):                   true,
+		fmt.Sprintf("%sShareFile", fileServicePath):               true,
+		fmt.Sprintf("%sUnshareFile", fileServicePath):             true,
+		fmt.Sprintf("%sCreateShareLink", fileServicePath):         true,
+		fmt.Sprintf("%sListShareLinks", fileServicePath):          true,
+		fmt.Sprintf("%sRevokeShareLink", fileServicePath):         true,
+		fmt.Sprintf("%sGetFile", fileServicePath):                 true,
+		fmt.Sprintf("%sBatchGetFiles", fileServicePath):           true,
+		fmt.Sprintf("%sCopy", fileServicePath):                    true,
 	}
 }
 
@@ -39,10 +74,47 @@ func main() {
 	serverAddress := flag.String("address", "", "the server address")
 
 	fileToUploadPath := flag.String("u", "", "file path in your system")
-	fileToDownloadId := flag.String("d", "", "id of the file to download")
+	fileToDownloadId := flag.String("d", "", "id of the file to download, delete, label, share or manage versions of, comma separated IDs with archive and stat")
 	numOfConcurrentRequests := flag.Int("num", 1, "number of concurrent request for upload/download")
-	fileOption := flag.String("option", "list", "upload, list, download")
+	fileOption := flag.String("option", "list", "upload, list, download, delete, usage, versions, restore, prune, trash, untrash, empty-trash, archive, upload-dir, mkdir, move, rmdir, label, search, watch, share, unshare, shared, link, links, revoke-link, get-shared, stat, copy")
 	clientNum := flag.String("test", "1", "for testing")
+	offset := flag.Uint64("offset", 0, "position of the first byte to download")
+	length := flag.Uint64("length", 0, "number of bytes to download, 0 means up to the end of the file")
+	version := flag.Uint("version", 0, "version of the file to download or restore, 0 means the current one")
+	keep := flag.Uint("keep", 0, "number of previous versions to keep when pruning")
+	resumable := flag.Bool("resumable", false, "resume interrupted uploads instead of starting over")
+	parallel := flag.Int("parallel", 0, "upload the file in parts, sending this many parts at once")
+	partSize := flag.Int64("part-size", 8<<20, "size of the parts in bytes when uploading with -parallel")
+	chunkSize := flag.Int("chunk-size", 0, "size of the chunks to send and receive in bytes, 0 means the size preferred by the server")
+	compression := flag.String("compress", "", "compress uploads and downloads on the wire: gzip, zstd")
+	archiveFormat := flag.String("format", "zip", "format of the archive: zip, tar.gz")
+	archiveOut := flag.String("out", "", "where to write the archive, - means stdout")
+	jobs := flag.Int("jobs", 4, "number of files uploaded at once with upload-dir")
+	folder := flag.String("path", "", "folder to list, create, move, delete, watch or archive")
+	destination := flag.String("to", "", "folder to move the file or the folder -path to, or to copy the file to")
+	children := flag.Bool("children", false, "list only what is directly in the folder -path")
+	order := flag.String("order", "", "order of listed files: name, size, created_at or updated_at, optionally followed by desc")
+	recursive := flag.Bool("r", false, "delete the folder with everything in it")
+	permanent := flag.Bool("permanent", false, "delete the file for good instead of moving it to the trash")
+	labels := flag.String("labels", "", "comma separated key=value labels of uploaded files, labels to set with the label option or to search and watch for")
+	tags := flag.String("tags", "", "comma separated tags of uploaded files, the new tags with the label option or the tags of the files to archive")
+	unlabel := flag.String("unlabel", "", "comma separated keys of labels to remove with the label option")
+	name := flag.String("name", "", "search files by a name pattern like *.log, or a part of the name")
+	minSize := flag.Uint64("min-size", 0, "search files of at least this many bytes")
+	maxSize := flag.Uint64("max-size", 0, "search files of at most this many bytes, 0 means no limit")
+	createdAfter := flag.String("created-after", "", "search files created at or after this time, e.g. 2023-07-01 or 2023-07-01T15:04:05Z")
+	createdBefore := flag.String("created-before", "", "search files created before this time")
+	updatedAfter := flag.String("updated-after", "", "search files updated at or after this time")
+	updatedBefore := flag.String("updated-before", "", "search files updated before this time")
+	contentType := flag.String("type", "", "search files of a media type like image/png, or a top-level type like image/")
+	afterEvent := flag.Uint64("after", 0, "sequence number of the last event seen, watching resumes after it")
+	shareWith := flag.String("user", "", "user to share the file with, to stop sharing it with or to copy it for")
+	writeAccess := flag.Bool("write", false, "let the user the file is shared with change it as well")
+	shareLink := flag.String("link", "", "token of the share link to download with get-shared, or the ID of the link to revoke")
+	expires := flag.Duration("expires", 0, "time until the share link expires, 0 means a day")
+	maxDownloads := flag.Uint("max-downloads", 0, "number of downloads allowed through the share link, 0 means unlimited")
+	linkPassword := flag.String("password", "", "password of the share link to create or download with")
+	title := flag.String("title", "", "name of the copy, empty keeps the name of the original")
 	flag.Parse()
 
 	log.Printf("connecting to server %s", *serverAddress)
@@ -75,14 +147,104 @@ func main() {
 	}
 
 	fileClient := service.NewFileClient(cc2)
+	fileClient.SetChunkSize(*chunkSize)
+	err = fileClient.SetCompression(*compression)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fileLabels, err := parseLabels(*labels)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fileClient.SetLabels(fileLabels, splitList(*tags))
+	created, err := parseTimeRange(*createdAfter, *createdBefore)
+	if err != nil {
+		log.Fatal(err)
+	}
+	updated, err := parseTimeRange(*updatedAfter, *updatedBefore)
+	if err != nil {
+		log.Fatal(err)
+	}
+	search := &pb.SearchRequest{
+		Name:        *name,
+		MinSize:     *minSize,
+		MaxSize:     *maxSize,
+		Created:     created,
+		Updated:     updated,
+		ContentType: *contentType,
+		Labels:      fileLabels,
+	}
+	upload := chooseUpload(fileClient, *resumable, *parallel, *partSize)
+	permission := pb.Permission_READ
+	if *writeAccess {
+		permission = pb.Permission_WRITE
+	}
 	if *clientNum == "1" {
 		switch *fileOption {
 		case "upload":
-			testUploadFile(fileClient, username, *fileToUploadPath, *numOfConcurrentRequests)
+			testUploadFile(fileClient, username, *fileToUploadPath, *numOfConcurrentRequests, upload)
 		case "list":
-			testListFiles(fileClient, username)
+			testListFiles(fileClient, username, *folder, *children, *order)
 		case "download":
-			testDownloadFile(fileClient, *fileToDownloadId, *numOfConcurrentRequests)
+			testDownloadFile(fileClient, *fileToDownloadId, *numOfConcurrentRequests, uint32(*version), *offset, *length)
+		case "delete":
+			fileClient.Delete(*fileToDownloadId, *permanent)
+		case "usage":
+			fileClient.GetUsage(&pb.Owner{Name: username})
+		case "versions":
+			fileClient.ListVersions(*fileToDownloadId)
+		case "restore":
+			fileClient.RestoreVersion(*fileToDownloadId, uint32(*version))
+		case "prune":
+			fileClient.PruneVersions(*fileToDownloadId, uint32(*keep))
+		case "trash":
+			fileClient.ListTrash(&pb.Owner{Name: username})
+		case "untrash":
+			fileClient.RestoreFromTrash(*fileToDownloadId)
+		case "empty-trash":
+			fileClient.EmptyTrash(&pb.Owner{Name: username})
+		case "archive":
+			downloadArchive(fileClient, username, *fileToDownloadId, *folder, *tags, *archiveFormat, *archiveOut)
+		case "mkdir":
+			fileClient.CreateFolder(&pb.Owner{Name: username}, *folder)
+		case "move":
+			if *fileToDownloadId != "" {
+				fileClient.Move(*fileToDownloadId, *destination)
+			} else {
+				fileClient.MoveFolder(&pb.Owner{Name: username}, *folder, *destination)
+			}
+		case "rmdir":
+			fileClient.DeleteFolder(&pb.Owner{Name: username}, *folder, *recursive, *permanent)
+		case "label":
+			updateLabels(fileClient, *fileToDownloadId, fileLabels, *tags, *unlabel)
+		case "search":
+			search.Owner = &pb.Owner{Name: username}
+			fileClient.Search(search)
+		case "watch":
+			fileClient.Watch(&pb.Owner{Name: username}, *folder, fileLabels, *afterEvent)
+		case "share":
+			fileClient.ShareFile(*fileToDownloadId, &pb.Owner{Name: *shareWith}, permission)
+		case "unshare":
+			fileClient.UnshareFile(*fileToDownloadId, &pb.Owner{Name: *shareWith})
+		case "shared":
+			fileClient.ListSharedWithMe(&pb.Owner{Name: username}, *order)
+		case "link":
+			fileClient.CreateShareLink(*fileToDownloadId, *expires, uint32(*maxDownloads), *linkPassword)
+		case "links":
+			fileClient.ListShareLinks(&pb.Owner{Name: username}, *fileToDownloadId)
+		case "revoke-link":
+			fileClient.RevokeShareLink(*shareLink)
+		case "get-shared":
+			fileClient.DownloadShared(*shareLink, *linkPassword)
+		case "stat":
+			getFiles(fileClient, *fileToDownloadId)
+		case "copy":
+			fileClient.Copy(*fileToDownloadId, *destination, *title, &pb.Owner{Name: *shareWith})
+		case "upload-dir":
+			_, err = fileClient.UploadDirectory(&pb.Owner{Name: username}, *fileToUploadPath, *jobs)
+			if err != nil {
+				log.Printf("Couldn't upload directory: %v", err)
+			}
 		default:
 			log.Fatal("Invalid option")
 		}
@@ -90,11 +252,69 @@ func main() {
 	} else { // in case you need one more client or more
 		switch *fileOption {
 		case "upload":
-			testUploadFile(fileClient, username1, *fileToUploadPath, *numOfConcurrentRequests)
+			testUploadFile(fileClient, username1, *fileToUploadPath, *numOfConcurrentRequests, upload)
 		case "list":
-			testListFiles(fileClient, username1)
+			testListFiles(fileClient, username1, *folder, *children, *order)
 		case "download":
-			testDownloadFile(fileClient, *fileToDownloadId, *numOfConcurrentRequests)
+			testDownloadFile(fileClient, *fileToDownloadId, *numOfConcurrentRequests, uint32(*version), *offset, *length)
+		case "delete":
+			fileClient.Delete(*fileToDownloadId, *permanent)
+		case "usage":
+			fileClient.GetUsage(&pb.Owner{Name: username1})
+		case "versions":
+			fileClient.ListVersions(*fileToDownloadId)
+		case "restore":
+			fileClient.RestoreVersion(*fileToDownloadId, uint32(*version))
+		case "prune":
+			fileClient.PruneVersions(*fileToDownloadId, uint32(*keep))
+		case "trash":
+			fileClient.ListTrash(&pb.Owner{Name: username1})
+		case "untrash":
+			fileClient.RestoreFromTrash(*fileToDownloadId)
+		case "empty-trash":
+			fileClient.EmptyTrash(&pb.Owner{Name: username1})
+		case "archive":
+			downloadArchive(fileClient, username1, *fileToDownloadId, *folder, *tags, *archiveFormat, *archiveOut)
+		case "mkdir":
+			fileClient.CreateFolder(&pb.Owner{Name: username1}, *folder)
+		case "move":
+			if *fileToDownloadId != "" {
+				fileClient.Move(*fileToDownloadId, *destination)
+			} else {
+				fileClient.MoveFolder(&pb.Owner{Name: username1}, *folder, *destination)
+			}
+		case "rmdir":
+			fileClient.DeleteFolder(&pb.Owner{Name: username1}, *folder, *recursive, *permanent)
+		case "label":
+			updateLabels(fileClient, *fileToDownloadId, fileLabels, *tags, *unlabel)
+		case "search":
+			search.Owner = &pb.Owner{Name: username1}
+			fileClient.Search(search)
+		case "watch":
+			fileClient.Watch(&pb.Owner{Name: username1}, *folder, fileLabels, *afterEvent)
+		case "share":
+			fileClient.ShareFile(*fileToDownloadId, &pb.Owner{Name: *shareWith}, permission)
+		case "unshare":
+			fileClient.UnshareFile(*fileToDownloadId, &pb.Owner{Name: *shareWith})
+		case "shared":
+			fileClient.ListSharedWithMe(&pb.Owner{Name: username1}, *order)
+		case "link":
+			fileClient.CreateShareLink(*fileToDownloadId, *expires, uint32(*maxDownloads), *linkPassword)
+		case "links":
+			fileClient.ListShareLinks(&pb.Owner{Name: username1}, *fileToDownloadId)
+		case "revoke-link":
+			fileClient.RevokeShareLink(*shareLink)
+		case "get-shared":
+			fileClient.DownloadShared(*shareLink, *linkPassword)
+		case "stat":
+			getFiles(fileClient, *fileToDownloadId)
+		case "copy":
+			fileClient.Copy(*fileToDownloadId, *destination, *title, &pb.Owner{Name: *shareWith})
+		case "upload-dir":
+			_, err = fileClient.UploadDirectory(&pb.Owner{Name: username1}, *fileToUploadPath, *jobs)
+			if err != nil {
+				log.Printf("Couldn't upload directory: %v", err)
+			}
 		default:
 			log.Fatal("Invalid option")
 		}
@@ -102,32 +322,172 @@ func main() {
 
 }
 
-func testDownloadFile(fc *service.FileClient, path string, num int) {
+func testDownloadFile(fc *service.FileClient, path string, num int, version uint32, offset, length uint64) {
 	var wg sync.WaitGroup
 
 	for i := 0; i < num; i++ {
 		wg.Add(1)
 		go func(i int) {
-			fc.Download(path)
+			fc.DownloadVersion(path, version, offset, length)
 			wg.Done()
 		}(i)
 	}
 	wg.Wait()
 }
 
-func testUploadFile(fc *service.FileClient, name, path string, num int) {
+func testUploadFile(fc *service.FileClient, name, path string, num int, upload uploadFunc) {
 	var wg sync.WaitGroup
 
 	for i := 0; i < num; i++ {
 		wg.Add(1)
 		go func(i int) {
-			fc.UploadFile(&pb.Owner{Name: name}, path)
+			upload(&pb.Owner{Name: name}, path)
 			wg.Done()
 		}(i)
 	}
 	wg.Wait()
 }
 
-func testListFiles(fc *service.FileClient, name string) {
-	fc.ListFiles(&pb.Owner{Name: name})
+type uploadFunc func(user *pb.Owner, path string)
+
+// chooseUpload picks the upload method selected with the flags
+func chooseUpload(fc *service.FileClient, resumable bool, parallel int, partSize int64) uploadFunc {
+	switch {
+	case parallel > 0:
+		return func(user *pb.Owner, path string) {
+			fc.MultipartUpload(user, path, partSize, parallel)
+		}
+	case resumable:
+		return fc.ResumableUpload
+	default:
+		return fc.UploadFile
+	}
+}
+
+// getFiles prints the metadata of the files with the comma separated IDs
+func getFiles(fc *service.FileClient, ids string) {
+	fileIds := strings.Split(ids, ",")
+	if len(fileIds) == 1 {
+		fc.GetFile(fileIds[0])
+		return
+	}
+	fc.GetFiles(fileIds)
+}
+
+// downloadArchive downloads the files with the comma separated IDs, or all files of the user in the folder
+// with all of the comma separated tags if there are none
+func downloadArchive(fc *service.FileClient, name, ids, folder, tags, format, out string) {
+	formats := map[string]pb.ArchiveFormat{"zip": pb.ArchiveFormat_ZIP, "tar.gz": pb.ArchiveFormat_TAR_GZ}
+	archiveFormat, ok := formats[format]
+	if !ok {
+		log.Fatalf("Invalid archive format %q", format)
+	}
+
+	var fileIds []string
+	var user *pb.Owner
+	if ids == "" {
+		user = &pb.Owner{Name: name}
+	} else {
+		fileIds = strings.Split(ids, ",")
+	}
+
+	var w io.Writer = os.Stdout
+	if out != "-" {
+		if out == "" {
+			out = filepath.Join("temp_files", "archive."+format)
+		}
+		err := os.MkdirAll(filepath.Dir(out), os.ModePerm)
+		if err != nil {
+			log.Fatal("Couldn't create download folder: ", err)
+		}
+		f, err := os.Create(out)
+		if err != nil {
+			log.Fatal("Couldn't create archive file: ", err)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	err := fc.DownloadArchive(fileIds, user, folder, splitList(tags), archiveFormat, w)
+	if err != nil {
+		log.Printf("Couldn't download archive: %v", err)
+	}
+}
+
+func testListFiles(fc *service.FileClient, name, folder string, children bool, order string) {
+	fc.ListFolder(&pb.Owner{Name: name}, folder, children, order)
+}
+
+// parseLabels parses comma separated key=value labels
+func parseLabels(text string) (map[string]string, error) {
+	labels := make(map[string]string)
+	for _, label := range splitList(text) {
+		key, value, ok := strings.Cut(label, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid label %q, expected key=value", label)
+		}
+		labels[key] = value
+	}
+	return labels, nil
+}
+
+// splitList splits a comma separated list, an empty text is an empty list
+func splitList(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(text, ",")
+}
+
+// updateLabels sets the given labels of a file, removes the comma separated keys in unlabel
+// and replaces the tags if they are given
+func updateLabels(fc *service.FileClient, id string, labels map[string]string, tags, unlabel string) {
+	paths := make([]string, 0)
+	for key := range labels {
+		paths = append(paths, "labels."+key)
+	}
+	for _, key := range splitList(unlabel) {
+		paths = append(paths, "labels."+key)
+	}
+	if tags != "" {
+		paths = append(paths, "tags")
+	}
+	if len(paths) == 0 {
+		log.Fatal("Nothing to update, use -labels, -unlabel or -tags")
+	}
+
+	fc.UpdateMetadata(id, labels, splitList(tags), paths)
+}
+
+// parseTimeRange parses the bounds of a time range, an empty bound is open
+func parseTimeRange(start, end string) (*pb.TimeRange, error) {
+	if start == "" && end == "" {
+		return nil, nil
+	}
+
+	startTime, err := parseTime(start)
+	if err != nil {
+		return nil, err
+	}
+	endTime, err := parseTime(end)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.TimeRange{Start: startTime, End: endTime}, nil
+}
+
+// parseTime parses a date or an RFC 3339 time, an empty text is no time
+func parseTime(text string) (*timestamppb.Timestamp, error) {
+	if text == "" {
+		return nil, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, text)
+	if err != nil {
+		t, err = time.Parse("2006-01-02", text)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid time %q, expected a date like 2023-07-01 or an RFC 3339 time", text)
+	}
+	return timestamppb.New(t), nil
 }
@@ -6,6 +6,9 @@ import (
 	"log"
 	"net"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/Nextasy01/grpc-file-service/pb"
@@ -26,9 +29,40 @@ func accessibleMethods() map[string][]string {
 	const fileServicePath = "/file.service.FileService/"
 
 	return map[string][]string{
-		fmt.Sprintf("%sUpload", fileServicePath):   {"admin"},
-		fmt.Sprintf("%sDownload", fileServicePath): {"admin"},
-		fmt.Sprintf("%sList", fileServicePath):     {"admin"},
+		fmt.Sprintf("%sUpload", fileServicePath):                  {"admin"},
+		fmt.Sprintf("%sDownload", fileServicePath):                {"admin", "user"},
+		fmt.Sprintf("%sList", fileServicePath):                    {"admin", "user"},
+		fmt.Sprintf("%sDelete", fileServicePath):                  {"admin", "user"},
+		fmt.Sprintf("%sCreateUploadSession", fileServicePath):     {"admin"},
+		fmt.Sprintf("%sGetUploadSession", fileServicePath):        {"admin"},
+		fmt.Sprintf("%sResumeUpload", fileServicePath):            {"admin"},
+		fmt.Sprintf("%sGetUsage", fileServicePath):                {"admin"},
+		fmt.Sprintf("%sListVersions", fileServicePath):            {"admin", "user"},
+		fmt.Sprintf("%sRestoreVersion", fileServicePath):          {"admin", "user"},
+		fmt.Sprintf("%sPruneVersions", fileServicePath):           {"admin", "user"},
+		fmt.Sprintf("%sListTrash", fileServicePath):               {"admin", "user"},
+		fmt.Sprintf("%sRestoreFromTrash", fileServicePath):        {"admin", "user"},
+		fmt.Sprintf("%sEmptyTrash", fileServicePath):              {"admin", "user"},
+		fmt.Sprintf("%sInitiateMultipartUpload", fileServicePath): {"admin"},
+		fmt.Sprintf("%sUploadPart", fileServicePath):              {"admin"},
+		fmt.Sprintf("%sCompleteMultipartUpload", fileServicePath): {"admin"},
+		fmt.Sprintf("%sAbortMultipartUpload", fileServicePath):    {"admin"},
+		fmt.Sprintf("%sDownloadArchive", fileServicePath):         {"admin", "user"},
+		fmt.Sprintf("%sCreateFolder", fileServicePath):            {"admin", "user"},
+		fmt.Sprintf("%sMove", fileServicePath):                    {"admin", "user"},
+		fmt.Sprintf("%sDeleteFolder", fileServicePath):            {"admin", "user"},
+		fmt.Sprintf("%sUpdateMetadata", fileServicePath):          {"admin", "user"},
+		fmt.Sprintf("%sSearch", fileServicePath):                  {"admin", "user"},
+		fmt.Sprintf("%sWatch", fileServicePath):                   {"admin", "user"},
+		fmt.Sprintf("%sShareFile", fileServicePath):               {"admin", "user"},
+		fmt.Sprintf("%sUnshareFile", fileServicePath):             {"admin", "user"},
+		fmt.Sprintf("%sCreateShareLink", fileServicePath):         {"admin", "user"},
+		fmt.Sprintf("%sListShareLinks", fileServicePath):          {"admin", "user"},
+		fmt.Sprintf("%sRevokeShareLink", fileServicePath):         {"admin", "user"},
+		fmt.Sprintf("%sGetFile", fileServicePath):                 {"admin", "user"},
+		fmt.Sprintf("%sBatchGetFiles", fileServicePath):           {"admin", "user"},
+		fmt.Sprintf("%sCopy", fileServicePath):                    {"admin", "user"},
+		// DownloadShared is left out on purpose, the share link token is its only authorization
 	}
 }
 
@@ -49,8 +83,81 @@ func createUser(userStore service.UserStore, username, password, role string) er
 	return userStore.Save(user)
 }
 
+func newFileStore(storeType, dir string, dedup bool) (service.FileStore, error) {
+	switch storeType {
+	case "memory":
+		store := service.NewInMemoryFileStore(dir)
+		if dedup {
+			store.EnableDeduplication()
+		}
+		return store, nil
+	case "disk":
+		store, err := service.NewDiskFileStore(dir)
+		if err != nil {
+			return nil, err
+		}
+		if dedup {
+			store.EnableDeduplication()
+		}
+		return store, nil
+	default:
+		return nil, fmt.Errorf("unknown store type %q", storeType)
+	}
+}
+
+// parseLimits parses a comma separated list of name=size pairs, e.g. "admin=10GB,user=500MB"
+func parseLimits(value string) (map[string]uint64, error) {
+	limits := make(map[string]uint64)
+	if value == "" {
+		return limits, nil
+	}
+
+	for _, pair := range strings.Split(value, ",") {
+		name, size, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid limit %q, expected name=size", pair)
+		}
+
+		bytes, err := parseSize(size)
+		if err != nil {
+			return nil, err
+		}
+		limits[strings.TrimSpace(name)] = bytes
+	}
+	return limits, nil
+}
+
+// parseSize parses a number of bytes with an optional KB, MB or GB suffix
+func parseSize(value string) (uint64, error) {
+	value = strings.ToUpper(strings.TrimSpace(value))
+
+	multiplier := uint64(1)
+	for suffix, size := range map[string]uint64{"KB": 1 << 10, "MB": 1 << 20, "GB": 1 << 30} {
+		if strings.HasSuffix(value, suffix) {
+			value = strings.TrimSuffix(value, suffix)
+			multiplier = size
+			break
+		}
+	}
+
+	number, err := strconv.ParseUint(strings.TrimSpace(value), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size %q: %w", value, err)
+	}
+	return number * multiplier, nil
+}
+
 func main() {
 	port := flag.Int("port", 8080, "server port")
+	storeType := flag.String("store", "disk", "file metadata store: memory, disk")
+	dedup := flag.Bool("dedup", false, "store identical files only once")
+	versioning := flag.Bool("versioning", false, "keep previous versions when a file is uploaded again")
+	roleQuotas := flag.String("quota", "", "default storage limit per role, e.g. admin=10GB,user=1GB")
+	userQuotas := flag.String("user-quota", "", "storage limit per user overriding the role default, e.g. admin1=20GB")
+	sessionTTL := flag.Duration("session-ttl", 24*time.Hour, "time after which unused upload sessions are removed")
+	chunkSize := flag.String("chunk-size", "64KB", "chunk size the server prefers for streaming")
+	maxChunkSize := flag.String("max-chunk-size", "1MB", "largest chunk the server accepts")
+	trashRetention := flag.Duration("trash-retention", 30*24*time.Hour, "time after which files in the trash are deleted for good")
 	flag.Parse()
 
 	address := fmt.Sprintf("0.0.0.0:%d", *port)
@@ -59,20 +166,57 @@ func main() {
 		log.Fatal("cannot run the server: ", err)
 	}
 
-	fileStore := service.NewInMemoryFileStore("files")
-	fileServer := service.NewFileServer(fileStore)
-
+	fileStore, err := newFileStore(*storeType, "files", *dedup)
+	if err != nil {
+		log.Fatal("cannot open file store: ", err)
+	}
 	userStore := service.NewInMemoryUserStore()
 	err = seedUsers(userStore)
 	if err != nil {
 		log.Fatal("cannot seed users")
 	}
 
+	roleLimits, err := parseLimits(*roleQuotas)
+	if err != nil {
+		log.Fatal("cannot parse quotas: ", err)
+	}
+	userLimits, err := parseLimits(*userQuotas)
+	if err != nil {
+		log.Fatal("cannot parse user quotas: ", err)
+	}
+	quotas := service.NewQuotaManager(fileStore, userStore, roleLimits)
+	for username, limit := range userLimits {
+		quotas.SetUserLimit(username, limit)
+	}
+
+	service.NewTrashPurger(fileStore, *trashRetention).Start(time.Hour)
+
+	uploadSessions := service.NewUploadSessionStore(fileStore, *sessionTTL)
+	multipartUploads := service.NewMultipartUploadStore(filepath.Join(os.TempDir(), "grpc-file-service"), quotas, *sessionTTL)
+	fileServer := service.NewFileServer(fileStore, uploadSessions, multipartUploads, quotas)
+	if *versioning {
+		fileServer.EnableVersioning()
+	}
+	preferredChunk, err := parseSize(*chunkSize)
+	if err != nil {
+		log.Fatal("cannot parse chunk size: ", err)
+	}
+	maxChunk, err := parseSize(*maxChunkSize)
+	if err != nil {
+		log.Fatal("cannot parse maximum chunk size: ", err)
+	}
+	err = fileServer.SetChunkSizes(int(preferredChunk), int(maxChunk))
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	jwtManager := service.NewJWTManager(os.Getenv("Secret_Key"), 15*time.Minute)
+	fileServer.EnableShareLinks(service.NewShareLinkStore(), jwtManager)
 	authServer := service.NewAuthServer(userStore, jwtManager)
 	authInterceptor := service.NewAuthInterceptor(jwtManager, accessibleMethods())
 
 	grpcServer := grpc.NewServer(
+		grpc.MaxRecvMsgSize(int(maxChunk)+service.MessageOverhead),
 		grpc.ChainUnaryInterceptor(authInterceptor.Unary()),
 		grpc.ChainStreamInterceptor(authInterceptor.Stream()),
 	)
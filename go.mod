@@ -25,5 +25,6 @@ require (
 
 require (
 	github.com/golang-jwt/jwt/v5 v5.0.0
+	github.com/klauspost/compress v1.16.7
 	google.golang.org/grpc v1.56.2
 )
@@ -0,0 +1,101 @@
+package service
+
+import (
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/grpc/encoding/gzip"
+)
+
+// Compressions a FileClient can request for its streams. gRPC negotiates them
+// with the server, which answers with the compression the client used
+const (
+	Gzip = gzip.Name
+	Zstd = "zstd"
+)
+
+func init() {
+	encoding.RegisterCompressor(&zstdCompressor{})
+}
+
+// checkCompression returns an error if the compression isn't supported, "" means no compression
+func checkCompression(name string) error {
+	if name != "" && encoding.GetCompressor(name) == nil {
+		return fmt.Errorf("unknown compression %q, expected %s or %s", name, Gzip, Zstd)
+	}
+	return nil
+}
+
+// zstdCompressor implements encoding.Compressor reusing encoders and decoders between messages
+type zstdCompressor struct {
+	encoders sync.Pool
+	decoders sync.Pool
+}
+
+func (compressor *zstdCompressor) Name() string {
+	return Zstd
+}
+
+func (compressor *zstdCompressor) Compress(w io.Writer) (io.WriteCloser, error) {
+	encoder, ok := compressor.encoders.Get().(*zstd.Encoder)
+	if ok {
+		encoder.Reset(w)
+	} else {
+		var err error
+		encoder, err = zstd.NewWriter(w, zstd.WithEncoderConcurrency(1))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &zstdWriter{Encoder: encoder, pool: &compressor.encoders}, nil
+}
+
+func (compressor *zstdCompressor) Decompress(r io.Reader) (io.Reader, error) {
+	decoder, ok := compressor.decoders.Get().(*zstd.Decoder)
+	if ok {
+		err := decoder.Reset(r)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		var err error
+		decoder, err = zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &zstdReader{decoder: decoder, pool: &compressor.decoders}, nil
+}
+
+type zstdWriter struct {
+	*zstd.Encoder
+	pool *sync.Pool
+}
+
+func (w *zstdWriter) Close() error {
+	err := w.Encoder.Close()
+	w.pool.Put(w.Encoder)
+	return err
+}
+
+// zstdReader returns its decoder to the pool once the message is read
+type zstdReader struct {
+	decoder *zstd.Decoder
+	pool    *sync.Pool
+}
+
+func (r *zstdReader) Read(p []byte) (int, error) {
+	if r.decoder == nil {
+		return 0, io.EOF
+	}
+
+	n, err := r.decoder.Read(p)
+	if err == io.EOF {
+		r.pool.Put(r.decoder)
+		r.decoder = nil
+	}
+	return n, err
+}
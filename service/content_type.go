@@ -0,0 +1,34 @@
+package service
+
+import (
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+)
+
+// number of bytes used to detect the content type
+const sniffLen = 512
+
+// detectContentType sniffs the MIME type from the first bytes of a file.
+// Generic results are refined by the extension, e.g. a .json file isn't just text/plain
+func detectContentType(head []byte, extension string) string {
+	contentType := http.DetectContentType(head)
+	if contentType != "application/octet-stream" && !strings.HasPrefix(contentType, "text/plain") {
+		return contentType
+	}
+
+	if byExtension := mime.TypeByExtension(extension); extension != "" && byExtension != "" {
+		return byExtension
+	}
+	return contentType
+}
+
+// fileExtension returns the extension of a file name, names like .bashrc have none
+func fileExtension(title string) string {
+	extension := filepath.Ext(title)
+	if extension == title {
+		return ""
+	}
+	return extension
+}
@@ -0,0 +1,122 @@
+package service
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const journalFileName = ".metadata.journal"
+
+// DiskFileStore is a FileStore that keeps file metadata in an append-only journal
+// next to the stored files, so they are still available after a server restart
+type DiskFileStore struct {
+	*InMemoryFileStore
+}
+
+// NewDiskFileStore opens the store in dir and reloads the metadata of previously saved files
+func NewDiskFileStore(dir string) (*DiskFileStore, error) {
+	err := os.MkdirAll(dir, os.ModePerm)
+	if err != nil {
+		return nil, err
+	}
+
+	journal, state, err := openFileJournal(filepath.Join(dir, journalFileName))
+	if err != nil {
+		return nil, err
+	}
+
+	store := NewInMemoryFileStore(dir)
+	store.journal = journal
+	store.data = state.files
+	store.blobs = state.blobs
+	store.versions = state.versions
+	store.folders = state.folders
+	store.reopenUploads(state.uploads)
+
+	err = store.reconcile()
+	if err != nil {
+		journal.close()
+		return nil, err
+	}
+
+	log.Printf("Loaded %d files from %s", len(store.data), dir)
+	return &DiskFileStore{store}, nil
+}
+
+// reconcile makes the metadata and the file folder consistent after an unclean shutdown:
+// files whose contents are missing are forgotten, contents nobody refers to and unfinished uploads
+// which can't be resumed are removed
+func (store *InMemoryFileStore) reconcile() error {
+	for id, file := range store.data {
+		blob, ok := store.blobs[id]
+		if !ok {
+			// written before blob names were journaled
+			blob = blobName(file)
+			store.blobs[id] = blob
+		}
+
+		if _, err := os.Stat(filepath.Join(store.fileFolder, blob)); err != nil {
+			log.Printf("Contents of file %s are missing, dropping it", id)
+			delete(store.data, id)
+			delete(store.blobs, id)
+			delete(store.versions, id)
+			continue
+		}
+		store.refs[blob]++
+		store.account(file)
+		store.index.add(file)
+
+		versions := store.versions[id][:0]
+		for _, previous := range store.versions[id] {
+			if _, err := os.Stat(filepath.Join(store.fileFolder, previous.blob)); err != nil {
+				log.Printf("Contents of version %d of file %s are missing, dropping it", previous.file.GetVersion(), id)
+				continue
+			}
+			store.refs[previous.blob]++
+			store.accountVersion(previous.file)
+			versions = append(versions, previous)
+		}
+		store.versions[id] = versions
+	}
+
+	uploads := make(map[string]journalEntry, len(store.uploads))
+	resumable := make(map[string]bool, len(store.uploads))
+	for id, session := range store.uploads {
+		writer := session.writer.(*fileWriter)
+		blob := filepath.Base(writer.temp.Name())
+		entry, err := uploadEntry(id, writer.file, blob, writer.replaces, session.Offset)
+		if err != nil {
+			return err
+		}
+		uploads[id] = entry
+		resumable[blob] = true
+	}
+
+	entries, err := os.ReadDir(store.fileFolder)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || store.refs[name] > 0 || resumable[name] {
+			continue
+		}
+		if isUpload, _ := filepath.Match(uploadFilePattern, name); !isUpload && strings.HasPrefix(name, ".") {
+			continue
+		}
+		log.Printf("Removing unreferenced file %s", name)
+		err := os.Remove(filepath.Join(store.fileFolder, name))
+		if err != nil {
+			return err
+		}
+	}
+
+	return store.journal.compact(&journalState{files: store.data, blobs: store.blobs, versions: store.versions, folders: store.folders, uploads: uploads})
+}
+
+// Close flushes and closes the metadata journal
+func (store *DiskFileStore) Close() error {
+	return store.journal.close()
+}
@@ -0,0 +1,88 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Nextasy01/grpc-file-service/pb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// ErrEventsExpired is returned when events after a sequence number are no longer kept
+var ErrEventsExpired = errors.New("events are no longer available")
+
+// ErrUnknownSequence is returned for sequence numbers no event has got yet
+var ErrUnknownSequence = errors.New("unknown sequence number")
+
+// number of recent events kept for watchers to resume from
+const eventFeedSize = 10000
+
+// eventFeed keeps the recent events of the files of a store and wakes up watchers when new ones arrive
+type eventFeed struct {
+	mutex   sync.Mutex
+	events  []*pb.FileEvent // recent events, oldest first
+	next    uint64          // sequence number of the next event
+	changed chan struct{}   // closed when the next event arrives
+}
+
+// newEventFeed starts the sequence numbers at the current time, so they keep increasing
+// when the server restarts and watchers can't resume from events which were lost
+func newEventFeed() *eventFeed {
+	return &eventFeed{
+		next:    uint64(time.Now().UnixNano()),
+		changed: make(chan struct{}),
+	}
+}
+
+func (feed *eventFeed) publish(eventType pb.FileEventType, file *pb.File, previousPath string) {
+	feed.mutex.Lock()
+	defer feed.mutex.Unlock()
+
+	event := &pb.FileEvent{
+		Sequence:     feed.next,
+		Type:         eventType,
+		File:         file,
+		PreviousPath: previousPath,
+		Time:         timestamppb.Now(),
+	}
+	feed.next++
+
+	if len(feed.events) == eventFeedSize {
+		feed.events = append(feed.events[:0], feed.events[1:]...)
+	}
+	feed.events = append(feed.events, event)
+
+	close(feed.changed)
+	feed.changed = make(chan struct{})
+}
+
+// last returns the sequence number of the latest event
+func (feed *eventFeed) last() uint64 {
+	feed.mutex.Lock()
+	defer feed.mutex.Unlock()
+
+	return feed.next - 1
+}
+
+// since returns the events after the given sequence number and a channel which is closed when more events arrive
+func (feed *eventFeed) since(sequence uint64) ([]*pb.FileEvent, <-chan struct{}, error) {
+	feed.mutex.Lock()
+	defer feed.mutex.Unlock()
+
+	if sequence >= feed.next {
+		return nil, nil, fmt.Errorf("%w: the latest event has sequence number %d", ErrUnknownSequence, feed.next-1)
+	}
+
+	first := feed.next
+	if len(feed.events) > 0 {
+		first = feed.events[0].GetSequence()
+	}
+	if sequence+1 < first {
+		return nil, nil, fmt.Errorf("%w: the oldest event has sequence number %d", ErrEventsExpired, first)
+	}
+
+	events := feed.events[len(feed.events)-int(feed.next-sequence-1):]
+	return append([]*pb.FileEvent(nil), events...), feed.changed, nil
+}
@@ -0,0 +1,39 @@
+package service
+
+import (
+	"sync"
+	"time"
+)
+
+// expiryLoop removes the expired entries of a store in the background until it's stopped
+type expiryLoop struct {
+	stop chan struct{}
+	once sync.Once
+}
+
+// startExpiryLoop calls removeExpired every interval
+func startExpiryLoop(interval time.Duration, removeExpired func()) *expiryLoop {
+	loop := &expiryLoop{stop: make(chan struct{})}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				removeExpired()
+			case <-loop.stop:
+				return
+			}
+		}
+	}()
+
+	return loop
+}
+
+// Stop ends the loop, it may be called more than once
+func (loop *expiryLoop) Stop() {
+	loop.once.Do(func() {
+		close(loop.stop)
+	})
+}
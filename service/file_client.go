@@ -3,23 +3,52 @@ package service
 import (
 	"bufio"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"log"
+	"math"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/Nextasy01/grpc-file-service/pb"
 	"github.com/google/uuid"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
+// maximum number of attempts to finish a resumable upload or to send a part of a multipart upload
+const uploadAttempts = 5
+
+// chunk size used with servers which don't advertise their chunk sizes
+const fallbackChunkSize = 1024
+
+// number of files requested at once when listing
+const listPageSize = 100
+
+// longest wait before reconnecting to watch files
+const maxWatchWait = 30 * time.Second
+
 type FileClient struct {
 	service              pb.FileServiceClient
+	chunkSize            int               // 0 means the preferred size of the server
+	maxChunkSize         atomic.Int64      // maximum chunk size advertised by the server, 0 until a stream told it
+	compression          string            // "" means the streams aren't compressed
+	labels               map[string]string // labels of uploaded files
+	tags                 []string          // tags of uploaded files
 	requestUploadCount   atomic.Int32
 	requestDownloadCount atomic.Int32
 	requestListCount     atomic.Int32
@@ -30,7 +59,88 @@ func NewFileClient(cc *grpc.ClientConn) *FileClient {
 	return &FileClient{service: service}
 }
 
+// SetChunkSize sets the size of the chunks to send and receive instead of the preferred size of the server,
+// the server still caps it by its maximum chunk size
+func (fileClient *FileClient) SetChunkSize(size int) {
+	fileClient.chunkSize = size
+}
+
+// SetCompression makes uploads and downloads travel compressed with gzip or zstd, "" turns compression off.
+// Stored files and reported sizes stay uncompressed
+func (fileClient *FileClient) SetCompression(name string) error {
+	err := checkCompression(name)
+	if err != nil {
+		return err
+	}
+
+	fileClient.compression = name
+	return nil
+}
+
+// SetLabels sets the labels and tags of the files uploaded from now on
+func (fileClient *FileClient) SetLabels(labels map[string]string, tags []string) {
+	fileClient.labels = labels
+	fileClient.tags = tags
+}
+
+// streamOptions returns the call options of upload and download streams
+func (fileClient *FileClient) streamOptions() []grpc.CallOption {
+	if fileClient.compression == "" {
+		return nil
+	}
+	return []grpc.CallOption{grpc.UseCompressor(fileClient.compression)}
+}
+
+// downloadOptions returns the call options of download streams, the received messages may be as large
+// as the chunks the server sends instead of the 4MB gRPC allows by default
+func (fileClient *FileClient) downloadOptions() []grpc.CallOption {
+	limit := math.MaxInt32
+	if max := fileClient.maxChunkSize.Load(); max > 0 {
+		limit = int(max) + MessageOverhead
+	} else if fileClient.chunkSize > 0 {
+		// the server never sends larger chunks than requested
+		limit = fileClient.chunkSize + MessageOverhead
+	}
+	return append(fileClient.streamOptions(), grpc.MaxCallRecvMsgSize(limit))
+}
+
+// rememberChunkSizes keeps the maximum chunk size the server advertised for the stream, ok = false if it didn't
+func (fileClient *FileClient) rememberChunkSizes(md metadata.MD) (preferred, max int, ok bool) {
+	preferred, max, ok = ChunkSizeFromMetadata(md)
+	if ok {
+		fileClient.maxChunkSize.Store(int64(max))
+	}
+	return preferred, max, ok
+}
+
+// uploadChunkSize picks the size of the chunks to send based on the sizes the server advertised for the stream
+func (fileClient *FileClient) uploadChunkSize(stream grpc.ClientStream) int {
+	md, err := stream.Header()
+	if err != nil {
+		return fallbackChunkSize
+	}
+
+	preferred, max, ok := fileClient.rememberChunkSizes(md)
+	if !ok {
+		return fallbackChunkSize
+	}
+
+	if fileClient.chunkSize > 0 {
+		preferred = fileClient.chunkSize
+	}
+	if preferred > max {
+		preferred = max
+	}
+	return preferred
+}
+
 func (fileClient *FileClient) ListFiles(user *pb.Owner) {
+	fileClient.ListFolder(user, "", false, "")
+}
+
+// ListFolder lists the files and folders in a folder, with childrenOnly the subfolders aren't listed recursively.
+// orderBy is described in pb.ListFilesRequest
+func (fileClient *FileClient) ListFolder(user *pb.Owner, folder string, childrenOnly bool, orderBy string) {
 	fileClient.requestListCount.Add(1) // incrementing concurent request count
 	defer fileClient.requestListCount.Add(-1)
 
@@ -41,18 +151,88 @@ func (fileClient *FileClient) ListFiles(user *pb.Owner) {
 			break
 		}
 	}
+	req := &pb.ListFilesRequest{
+		User:           user,
+		Folder:         folder,
+		ChildrenOnly:   childrenOnly,
+		IncludeFolders: true,
+		PageSize:       listPageSize,
+		OrderBy:        orderBy,
+	}
+
+	log.Println("Your files:")
+	fileClient.listPages(req)
+}
+
+// ListSharedWithMe lists the files other users shared with the user
+func (fileClient *FileClient) ListSharedWithMe(user *pb.Owner, orderBy string) {
+	req := &pb.ListFilesRequest{User: user, SharedWithMe: true, PageSize: listPageSize, OrderBy: orderBy}
+
+	log.Println("Files shared with you:")
+	fileClient.listPages(req)
+}
+
+// listPages prints every page of a listing
+func (fileClient *FileClient) listPages(req *pb.ListFilesRequest) {
+	for {
+		nextPageToken, err := fileClient.listPage(req)
+		if err != nil {
+			log.Println("Cannot receive response from server: ", err)
+			return
+		}
+		if nextPageToken == "" {
+			return
+		}
+		req.PageToken = nextPageToken
+	}
+}
+
+// listPage prints a page of a listing and returns the token of the next page, "" if it was the last one
+func (fileClient *FileClient) listPage(req *pb.ListFilesRequest) (string, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	req := &pb.ListFilesRequest{User: user}
-
 	stream, err := fileClient.service.List(ctx, req)
+	if err != nil {
+		return "", err
+	}
+
+	nextPageToken := ""
+	for {
+		res, err := stream.Recv()
+		if err == io.EOF {
+			return nextPageToken, nil
+		}
+		if err != nil {
+			return "", err
+		}
+		if folder := res.GetFolder(); folder != nil {
+			fmt.Printf("Folder: %s/\n", folder.GetPath())
+			continue
+		}
+		file := res.GetFile()
+		owner := ""
+		if file.GetOwner().GetName() != req.GetUser().GetName() {
+			owner = " - Owner: " + file.GetOwner().GetName()
+		}
+		fmt.Printf("ID: %s - Name: %s - Size: %d - Date: %s%s%s\n", file.GetId(), fullPath(file), file.GetSize(),
+			file.GetCreatedAt().AsTime().UTC(), owner, formatLabels(file.GetLabels(), file.GetTags()))
+		nextPageToken = res.GetNextPageToken()
+	}
+}
+
+// Search lists the files matching the query
+func (fileClient *FileClient) Search(query *pb.SearchRequest) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream, err := fileClient.service.Search(ctx, query)
 	if err != nil {
 		log.Println(err)
 		return
 	}
 
-	log.Println("Your files:")
+	log.Println("Found files:")
 	for {
 		res, err := stream.Recv()
 		if err == io.EOF {
@@ -63,9 +243,134 @@ func (fileClient *FileClient) ListFiles(user *pb.Owner) {
 			return
 		}
 		file := res.GetFile()
-		fmt.Printf("ID: %s - Name: %s - Date: %s\n", file.GetId(), file.GetTitle(), file.GetCreatedAt().AsTime().UTC())
+		fmt.Printf("ID: %s - Name: %s - Size: %d - Type: %s - Date: %s%s\n", file.GetId(), fullPath(file), file.GetSize(),
+			file.GetContentType(), file.GetCreatedAt().AsTime().UTC(), formatLabels(file.GetLabels(), file.GetTags()))
+	}
+}
+
+// Watch prints the changes of the files of a user until the server ends the stream, reconnecting when the connection is lost.
+// afterSequence resumes after an event seen before, 0 starts with the next change
+func (fileClient *FileClient) Watch(user *pb.Owner, folder string, labels map[string]string, afterSequence uint64) {
+	req := &pb.WatchRequest{Owner: user, Folder: folder, Labels: labels, AfterSequence: afterSequence}
+
+	wait := time.Second
+	for {
+		received, err := fileClient.watch(req)
+		if !isRetryable(err) {
+			log.Printf("Stopped watching: %v", err)
+			return
+		}
+		if received {
+			wait = time.Second
+		}
+
+		log.Printf("Connection lost, resuming after event %d in %s: %v", req.GetAfterSequence(), wait, err)
+		time.Sleep(wait)
+		if wait < maxWatchWait {
+			wait *= 2
+		}
+	}
+}
+
+// watch prints events until the stream fails and reports whether any event was received.
+// The sequence number of the request is advanced to the last event
+func (fileClient *FileClient) watch(req *pb.WatchRequest) (bool, error) {
+	// no deadline here, the stream stays open while files change
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stream, err := fileClient.service.Watch(ctx, req)
+	if err != nil {
+		return false, err
+	}
+
+	received := false
+	for {
+		event, err := stream.Recv()
+		if err != nil {
+			return received, err
+		}
+		received = true
+		req.AfterSequence = event.GetSequence()
+
+		file := event.GetFile()
+		moved := ""
+		if event.GetType() == pb.FileEventType_MOVED {
+			moved = fmt.Sprintf(" (from %s)", event.GetPreviousPath())
+		}
+		fmt.Printf("Event: %d - %s - ID: %s - Name: %s%s - Date: %s\n", event.GetSequence(), event.GetType(),
+			file.GetId(), fullPath(file), moved, event.GetTime().AsTime().UTC())
+	}
+}
+
+func (fileClient *FileClient) CreateFolder(user *pb.Owner, path string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	folder, err := fileClient.service.CreateFolder(ctx, &pb.CreateFolderRequest{Owner: user, Path: path})
+	if err != nil {
+		log.Printf("Couldn't create folder: %v", err)
+		return
+	}
+
+	log.Printf("Created folder %s", folder.GetPath())
+}
+
+// Move moves the file with the given ID into the folder destination
+func (fileClient *FileClient) Move(id, destination string) {
+	fileClient.move(&pb.MoveRequest{FileId: id, Destination: destination})
+}
+
+// Copy copies the file into the folder destination of the user, empty values keep the folder and the title
+// of the original and an empty user name means the caller
+func (fileClient *FileClient) Copy(id, destination, title string, user *pb.Owner) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	res, err := fileClient.service.Copy(ctx, &pb.CopyRequest{FileId: id, Destination: destination, Title: title, Owner: user})
+	if err != nil {
+		log.Printf("Couldn't copy file: %v", err)
+		return
+	}
+
+	file := res.GetFile()
+	log.Printf("Copied file %s to %s of %s as file %s", id, fullPath(file), file.GetOwner().GetName(), file.GetId())
+}
+
+// MoveFolder moves a folder with everything in it to the path destination
+func (fileClient *FileClient) MoveFolder(user *pb.Owner, folder, destination string) {
+	fileClient.move(&pb.MoveRequest{Owner: user, Folder: folder, Destination: destination})
+}
+
+func (fileClient *FileClient) move(req *pb.MoveRequest) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	res, err := fileClient.service.Move(ctx, req)
+	if err != nil {
+		log.Printf("Couldn't move: %v", err)
+		return
+	}
+
+	log.Printf("Moved %d files to %s", res.GetMoved(), req.GetDestination())
+}
+
+func (fileClient *FileClient) DeleteFolder(user *pb.Owner, path string, recursive, permanent bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	res, err := fileClient.service.DeleteFolder(ctx, &pb.DeleteFolderRequest{
+		Owner:     user,
+		Path:      path,
+		Recursive: recursive,
+		Permanent: permanent,
+	})
+	if err != nil {
+		log.Printf("Couldn't delete folder: %v", err)
+		return
 	}
 
+	log.Printf("Deleted folder %s with %d files", path, res.GetFiles())
 }
 
 func (fileClient *FileClient) UploadFile(user *pb.Owner, path string) {
@@ -80,147 +385,923 @@ func (fileClient *FileClient) UploadFile(user *pb.Owner, path string) {
 		}
 	}
 
-	file, err := os.Open(path)
+	res, err := fileClient.uploadFile(user, path, "")
 	if err != nil {
-		log.Printf("Wrong path or file doesn't exists in %s path: %v", path, err)
+		log.Println(err)
 		return
 	}
+
+	log.Printf("File successfully uploaded with id: %s and size: %d bytes", res.GetFile().GetId(), res.GetSize())
+}
+
+// uploadFile uploads the file at path and stores it in the directory dir on the server
+func (fileClient *FileClient) uploadFile(user *pb.Owner, path, dir string) (*pb.UploadFileResponse, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("wrong path or file doesn't exists in %s path: %w", path, err)
+	}
 	defer file.Close()
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	// no deadline here, large files may take long to upload
+	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	stream, err := fileClient.service.Upload(ctx)
+	stream, err := fileClient.service.Upload(ctx, fileClient.streamOptions()...)
 	if err != nil {
-		log.Printf("Couldn't upload file, try again: %v", err)
-		return
+		return nil, fmt.Errorf("couldn't upload file, try again: %w", err)
 	}
 
 	newId, _ := uuid.NewRandom()
 	fileStruct, _ := file.Stat()
 	fileSize := fileStruct.Size()
 
+	checksum, err := fileChecksum(file)
+	if err != nil {
+		return nil, fmt.Errorf("cannot calculate checksum of the file: %w", err)
+	}
+
 	req := &pb.UploadFileRequest{
 		File: &pb.File{
 			Id:        newId.String(),
 			Title:     file.Name(),
+			Path:      dir,
 			Size:      uint64(fileSize),
 			CreatedAt: timestamppb.Now(),
 			UpdatedAt: timestamppb.Now(),
 			Owner:     user,
+			Checksum:  checksum,
+			Labels:    fileClient.labels,
+			Tags:      fileClient.tags,
 		},
 	}
 
 	err = stream.Send(req)
-	if err != nil {
-		log.Printf("Couldn't send file to the server, try again later: %v", err)
-		return
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("couldn't send file to the server, try again later: %w", err)
 	}
 
 	reader := bufio.NewReader(file)
-	buf := make([]byte, 1024)
+	buf := make([]byte, fileClient.uploadChunkSize(stream))
 
-	for {
-		n, err := reader.Read(buf)
+	for err == nil {
+		var n int
+		n, err = reader.Read(buf)
 		if err == io.EOF {
-			log.Println("No more data")
 			break
 		}
-
 		if err != nil {
-			log.Printf("Cannot read a chunk of data to buffer, try again: %v", err)
-			return
+			return nil, fmt.Errorf("cannot read a chunk of data to buffer, try again: %w", err)
 		}
 
-		req := &pb.UploadFileRequest{Chunk: buf[:n]}
+		err = stream.Send(&pb.UploadFileRequest{Chunk: buf[:n]})
+	}
+
+	// a failed Send returns io.EOF, the actual error comes with the response
+	res, err := stream.CloseAndRecv()
+	if err != nil {
+		return nil, fmt.Errorf("cannot receive response: %w", err)
+	}
+	return res, nil
+}
+
+// UploadSummary reports the outcome of a directory upload
+type UploadSummary struct {
+	Uploaded int
+	Bytes    uint64
+	Failed   map[string]error // by path relative to the uploaded directory
+}
+
+// UploadDirectory uploads all files in the tree of root, keeping their paths relative to root.
+// Up to parallelism files are sent at once
+func (fileClient *FileClient) UploadDirectory(user *pb.Owner, root string, parallelism int) (*UploadSummary, error) {
+	if parallelism <= 0 {
+		return nil, errors.New("parallelism must be positive")
+	}
 
-		err = stream.Send(req)
+	paths := make([]string, 0)
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
 		if err != nil {
-			log.Printf("Cannot send a chunk of data to server, try again: %v", err)
-			return
+			return err
 		}
+		if entry.Type().IsRegular() {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	res, err := stream.CloseAndRecv()
-	if err != nil {
-		log.Printf("Cannot receive response: %v", err)
-		return
+	summary := &UploadSummary{Failed: make(map[string]error)}
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	jobs := make(chan string)
+
+	for i := 0; i < parallelism; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for path := range jobs {
+				rel, _ := filepath.Rel(root, path)
+				res, err := fileClient.uploadFile(user, path, filepath.ToSlash(filepath.Dir(rel)))
+
+				mutex.Lock()
+				if err != nil {
+					summary.Failed[filepath.ToSlash(rel)] = err
+				} else {
+					summary.Uploaded++
+					summary.Bytes += res.GetSize()
+				}
+				mutex.Unlock()
+			}
+		}()
 	}
 
-	log.Printf("File successfully uploaded with id: %s and size: %d bytes", res.GetFile().GetId(), res.GetSize())
+	for _, path := range paths {
+		jobs <- path
+	}
+	close(jobs)
+	wg.Wait()
+
+	log.Printf("Uploaded %d files with %d bytes from %s, %d failed", summary.Uploaded, summary.Bytes, root, len(summary.Failed))
+	for path, err := range summary.Failed {
+		log.Printf("Failed to upload %s: %v", path, err)
+	}
+	return summary, nil
 }
 
-func (fileClient *FileClient) Download(id string) {
-	fileClient.requestDownloadCount.Add(1) // incrementing concurent request count
-	defer fileClient.requestDownloadCount.Add(-1)
+// UploadFile variant which continues from the last stored byte when the upload is interrupted
+func (fileClient *FileClient) ResumableUpload(user *pb.Owner, path string) {
+	fileClient.requestUploadCount.Add(1) // incrementing concurent request count
+	defer fileClient.requestUploadCount.Add(-1)
 
 	for {
-		if fileClient.requestDownloadCount.Load() > downloadLimit {
-			log.Printf("Download limit(%d) is exceeded. You can upload only up to 10 files at once, please wait while other files finish downloading", downloadLimit)
+		if fileClient.requestUploadCount.Load() > uploadLimit {
+			log.Printf("Upload limit(%d) is exceeded. Please wait while other files finish uploading", uploadLimit)
 		} else {
 			break
 		}
 	}
-	log.Println("Starting to download the file")
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
 
-	stream, err := fileClient.service.Download(ctx, &pb.DownloadFileRequest{
-		FileId: id,
-	})
+	file, err := os.Open(path)
 	if err != nil {
-		log.Printf("Couldn't download file, try again: %v", err)
+		log.Printf("Wrong path or file doesn't exists in %s path: %v", path, err)
 		return
 	}
+	defer file.Close()
 
-	md, err := stream.Header()
+	fileStruct, _ := file.Stat()
+	checksum, err := fileChecksum(file)
 	if err != nil {
-		log.Printf("Couldn't get file metadata, try again: %v", err)
+		log.Printf("Cannot calculate checksum of the file: %v", err)
 		return
 	}
 
-	r, w := io.Pipe()
-	defer r.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
 
-	// go routine to receive responses
-	go copyFromResponse(w, stream)
+	session, err := fileClient.service.CreateUploadSession(ctx, &pb.CreateUploadSessionRequest{
+		File: &pb.File{
+			Title:     file.Name(),
+			Size:      uint64(fileStruct.Size()),
+			CreatedAt: timestamppb.Now(),
+			UpdatedAt: timestamppb.Now(),
+			Owner:     user,
+			Checksum:  checksum,
+			Labels:    fileClient.labels,
+			Tags:      fileClient.tags,
+		},
+	})
+	if err != nil {
+		log.Printf("Couldn't start upload, try again: %v", err)
+		return
+	}
 
-	log.Println("Creating new file")
+	wait := time.Second
+	for attempt := 1; ; attempt++ {
+		res, err := fileClient.resumeUpload(session.GetSessionId(), file)
+		if err == nil && res.GetFile() != nil {
+			log.Printf("File successfully uploaded with id: %s and size: %d bytes", res.GetFile().GetId(), res.GetFile().GetSize())
+			return
+		}
 
-	time.Sleep(500 * time.Millisecond) // this is needed to surely randomize new generated UUID
-	randID, _ := uuid.NewUUID()        // since NewUUID based on current time, multiple goroutines may execute at the same time leading on the same UUID
+		if err != nil && !isRetryable(err) {
+			log.Printf("Cannot upload file: %v", err)
+			return
+		}
+		if attempt == uploadAttempts {
+			log.Printf("Giving up uploading %s after %d attempts: %v", path, attempt, err)
+			return
+		}
 
-	downloadFolder := "temp_files"
+		log.Printf("Upload of %s was interrupted, resuming in %s: %v", path, wait, err)
+		time.Sleep(wait)
+		wait *= 2
+	}
+}
 
-	// create folder/directory if not exists
-	if _, err := os.Stat(downloadFolder); errors.Is(err, os.ErrNotExist) {
-		err := os.Mkdir(downloadFolder, os.ModePerm)
-		if err != nil {
-			log.Printf("Couldn't create download folder: %v", err)
-		}
+// resumeUpload sends the rest of the file starting from the offset stored on the server
+func (fileClient *FileClient) resumeUpload(sessionId string, file *os.File) (*pb.ResumeUploadResponse, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	session, err := fileClient.service.GetUploadSession(ctx, &pb.GetUploadSessionRequest{SessionId: sessionId})
+	cancel()
+	if err != nil {
+		return nil, err
 	}
 
-	newFileName := fmt.Sprintf("%s-%s", randID.String(), md.Get("title")[0])
-	filePath := filepath.Join(downloadFolder, newFileName)
-	f, err := os.Create(filePath)
+	_, err = file.Seek(int64(session.GetOffset()), io.SeekStart)
 	if err != nil {
-		log.Printf("Couldn't create file: %v", err)
-		return
+		return nil, err
 	}
-	defer f.Close()
-	log.Println("copying contents from reader pipe to file")
-	_, _ = f.ReadFrom(r)
 
+	// no deadline here, large files may take long to upload
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+
+	stream, err := fileClient.service.ResumeUpload(ctx, fileClient.streamOptions()...)
 	if err != nil {
-		log.Printf("Receive error from response: %v", err)
-		return
+		return nil, err
+	}
+
+	err = stream.Send(&pb.ResumeUploadRequest{SessionId: sessionId, Offset: session.GetOffset()})
+	if err != nil && err != io.EOF {
+		return nil, err
 	}
 
-	log.Printf("Successfully downloaded file with name: %s and size: %s bytes!", newFileName, md.Get("size")[0])
+	reader := bufio.NewReader(file)
+	buf := make([]byte, fileClient.uploadChunkSize(stream))
+
+	for err == nil {
+		var n int
+		n, err = reader.Read(buf)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
 
+		err = stream.Send(&pb.ResumeUploadRequest{Chunk: buf[:n]})
+	}
+
+	// a failed Send returns io.EOF, the actual error comes with the response
+	return stream.CloseAndRecv()
+}
+
+// isRetryable reports whether a failed request may succeed when it's repeated
+func isRetryable(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.Aborted, codes.Unknown:
+		return true
+	default:
+		return false
+	}
+}
+
+// MultipartUpload uploads the file in parts of partSize bytes, sending up to parallelism parts at once
+func (fileClient *FileClient) MultipartUpload(user *pb.Owner, path string, partSize int64, parallelism int) {
+	if partSize <= 0 || parallelism <= 0 {
+		log.Printf("Part size and parallelism must be positive")
+		return
+	}
+
+	fileClient.requestUploadCount.Add(1) // incrementing concurent request count
+	defer fileClient.requestUploadCount.Add(-1)
+
+	for {
+		if fileClient.requestUploadCount.Load() > uploadLimit {
+			log.Printf("Upload limit(%d) is exceeded. Please wait while other files finish uploading", uploadLimit)
+		} else {
+			break
+		}
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		log.Printf("Wrong path or file doesn't exists in %s path: %v", path, err)
+		return
+	}
+	defer file.Close()
+
+	fileStruct, _ := file.Stat()
+	checksum, err := fileChecksum(file)
+	if err != nil {
+		log.Printf("Cannot calculate checksum of the file: %v", err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	upload, err := fileClient.service.InitiateMultipartUpload(ctx, &pb.InitiateMultipartUploadRequest{
+		File: &pb.File{
+			Title:     file.Name(),
+			Size:      uint64(fileStruct.Size()),
+			CreatedAt: timestamppb.Now(),
+			UpdatedAt: timestamppb.Now(),
+			Owner:     user,
+			Checksum:  checksum,
+			Labels:    fileClient.labels,
+			Tags:      fileClient.tags,
+		},
+	})
+	if err != nil {
+		log.Printf("Couldn't start upload, try again: %v", err)
+		return
+	}
+
+	// an empty file is sent as a single empty part
+	count := (fileStruct.Size() + partSize - 1) / partSize
+	if count == 0 {
+		count = 1
+	}
+
+	parts := make([]*pb.CompletedPart, count)
+	numbers := make(chan uint32)
+	var failed atomic.Pointer[error]
+	var wg sync.WaitGroup
+
+	for i := 0; i < parallelism; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for number := range numbers {
+				if failed.Load() != nil {
+					continue
+				}
+
+				section := io.NewSectionReader(file, int64(number-1)*partSize, partSize)
+				part, err := fileClient.uploadPartWithRetries(upload.GetUploadId(), number, section)
+				if err != nil {
+					failed.CompareAndSwap(nil, &err)
+					continue
+				}
+				parts[number-1] = part
+			}
+		}()
+	}
+
+	for number := uint32(1); number <= uint32(count); number++ {
+		numbers <- number
+	}
+	close(numbers)
+	wg.Wait()
+
+	if err := failed.Load(); err != nil {
+		log.Printf("Cannot upload file: %v", *err)
+		fileClient.abortMultipartUpload(upload.GetUploadId())
+		return
+	}
+
+	// no deadline here, assembling large files may take long
+	res, err := fileClient.service.CompleteMultipartUpload(context.Background(), &pb.CompleteMultipartUploadRequest{
+		UploadId: upload.GetUploadId(),
+		Parts:    parts,
+	})
+	if err != nil {
+		log.Printf("Cannot complete upload: %v", err)
+		fileClient.abortMultipartUpload(upload.GetUploadId())
+		return
+	}
+
+	log.Printf("File successfully uploaded with id: %s and size: %d bytes in %d parts", res.GetFile().GetId(), res.GetFile().GetSize(), count)
+}
+
+// uploadPartWithRetries sends a part again while it fails with a retryable error
+func (fileClient *FileClient) uploadPartWithRetries(uploadId string, number uint32, section *io.SectionReader) (*pb.CompletedPart, error) {
+	wait := time.Second
+	for attempt := 1; ; attempt++ {
+		part, err := fileClient.uploadPart(uploadId, number, section)
+		if err == nil || !isRetryable(err) || attempt == uploadAttempts {
+			return part, err
+		}
+
+		log.Printf("Upload of part %d was interrupted, retrying in %s: %v", number, wait, err)
+		time.Sleep(wait)
+		wait *= 2
+	}
+}
+
+// uploadPart sends a part and returns it with the checksum calculated while sending,
+// so the server can tell if the stored part differs
+func (fileClient *FileClient) uploadPart(uploadId string, number uint32, section *io.SectionReader) (*pb.CompletedPart, error) {
+	_, err := section.Seek(0, io.SeekStart)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stream, err := fileClient.service.UploadPart(ctx, fileClient.streamOptions()...)
+	if err != nil {
+		return nil, err
+	}
+
+	err = stream.Send(&pb.UploadPartRequest{UploadId: uploadId, PartNumber: number})
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	hash := sha256.New()
+	reader := bufio.NewReader(io.TeeReader(section, hash))
+	buf := make([]byte, fileClient.uploadChunkSize(stream))
+
+	for err == nil {
+		var n int
+		n, err = reader.Read(buf)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		err = stream.Send(&pb.UploadPartRequest{Chunk: buf[:n]})
+	}
+
+	// a failed Send returns io.EOF, the actual error comes with the response
+	_, err = stream.CloseAndRecv()
+	if err != nil {
+		return nil, err
+	}
+	return &pb.CompletedPart{PartNumber: number, Checksum: hex.EncodeToString(hash.Sum(nil))}, nil
+}
+
+func (fileClient *FileClient) abortMultipartUpload(uploadId string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := fileClient.service.AbortMultipartUpload(ctx, &pb.AbortMultipartUploadRequest{UploadId: uploadId})
+	if err != nil {
+		log.Printf("Couldn't abort multipart upload %s: %v", uploadId, err)
+	}
+}
+
+func (fileClient *FileClient) Download(id string) {
+	fileClient.DownloadRange(id, 0, 0)
+}
+
+// DownloadRange downloads length bytes of the file starting at offset, length 0 means up to the end
+func (fileClient *FileClient) DownloadRange(id string, offset, length uint64) {
+	fileClient.DownloadVersion(id, 0, offset, length)
+}
+
+// DownloadVersion downloads a range of a version of the file, version 0 means the current one
+func (fileClient *FileClient) DownloadVersion(id string, version uint32, offset, length uint64) {
+	fileClient.requestDownloadCount.Add(1) // incrementing concurent request count
+	defer fileClient.requestDownloadCount.Add(-1)
+
+	for {
+		if fileClient.requestDownloadCount.Load() > downloadLimit {
+			log.Printf("Download limit(%d) is exceeded. You can upload only up to 10 files at once, please wait while other files finish downloading", downloadLimit)
+		} else {
+			break
+		}
+	}
+	log.Println("Starting to download the file")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream, err := fileClient.service.Download(ctx, &pb.DownloadFileRequest{
+		FileId:    id,
+		Offset:    offset,
+		Length:    length,
+		Version:   version,
+		ChunkSize: uint32(fileClient.chunkSize),
+	}, fileClient.downloadOptions()...)
+	if err != nil {
+		log.Printf("Couldn't download file, try again: %v", err)
+		return
+	}
+
+	fileClient.saveDownload(stream, offset > 0 || length > 0)
+}
+
+// DownloadShared downloads the file of a share link, no account is needed
+func (fileClient *FileClient) DownloadShared(token, password string) {
+	log.Println("Starting to download the shared file")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream, err := fileClient.service.DownloadShared(ctx, &pb.DownloadSharedRequest{
+		Token:     token,
+		Password:  password,
+		ChunkSize: uint32(fileClient.chunkSize),
+	}, fileClient.downloadOptions()...)
+	if err != nil {
+		log.Printf("Couldn't download shared file, try again: %v", err)
+		return
+	}
+
+	fileClient.saveDownload(stream, false)
+}
+
+// saveDownload saves a downloaded file in the temp_files folder, the checksum is only verified if the download isn't partial
+func (fileClient *FileClient) saveDownload(stream grpc.ClientStream, partial bool) {
+	md, err := stream.Header()
+	if err != nil {
+		log.Printf("Couldn't get file metadata, try again: %v", err)
+		return
+	}
+	fileClient.rememberChunkSizes(md)
+
+	r, w := io.Pipe()
+	defer r.Close()
+
+	// go routine to receive responses
+	go copyFromResponse(w, stream)
+
+	log.Println("Creating new file")
+
+	time.Sleep(500 * time.Millisecond) // this is needed to surely randomize new generated UUID
+	randID, _ := uuid.NewUUID()        // since NewUUID based on current time, multiple goroutines may execute at the same time leading on the same UUID
+
+	downloadFolder := "temp_files"
+
+	// create folder/directory if not exists
+	if _, err := os.Stat(downloadFolder); errors.Is(err, os.ErrNotExist) {
+		err := os.Mkdir(downloadFolder, os.ModePerm)
+		if err != nil {
+			log.Printf("Couldn't create download folder: %v", err)
+		}
+	}
+
+	newFileName := fmt.Sprintf("%s-%s", randID.String(), md.Get("title")[0])
+	filePath := filepath.Join(downloadFolder, newFileName)
+	f, err := os.Create(filePath)
+	if err != nil {
+		log.Printf("Couldn't create file: %v", err)
+		return
+	}
+	defer f.Close()
+	log.Println("copying contents from reader pipe to file")
+	hash := sha256.New()
+	_, err = io.Copy(io.MultiWriter(f, hash), r)
+
+	if err != nil {
+		log.Printf("Receive error from response: %v", err)
+		return
+	}
+
+	// the checksum covers the whole file only
+	if checksum := md.Get("checksum"); !partial && len(checksum) > 0 && checksum[0] != "" {
+		if !strings.EqualFold(checksum[0], hex.EncodeToString(hash.Sum(nil))) {
+			log.Printf("Downloaded file %s is corrupted: checksum doesn't match, removing it", newFileName)
+			f.Close()
+			os.Remove(filePath)
+			return
+		}
+	}
+
+	labels, tags := LabelsFromMetadata(md)
+	log.Printf("Successfully downloaded file with name: %s, type: %s and size: %s bytes!%s",
+		newFileName, strings.Join(md.Get("mime-type"), ""), md.Get("size")[0], formatLabels(labels, tags))
+
+}
+
+// DownloadArchive writes an archive of the files with the given IDs to w,
+// with a user all files of the user in the folder having all of the tags are added as well
+func (fileClient *FileClient) DownloadArchive(ids []string, user *pb.Owner, folder string, tags []string, format pb.ArchiveFormat, w io.Writer) error {
+	// no deadline here, archives of many files may take long to download
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stream, err := fileClient.service.DownloadArchive(ctx, &pb.DownloadArchiveRequest{
+		FileIds: ids,
+		Owner:   user,
+		Format:  format,
+		Folder:  folder,
+		Tags:    tags,
+	}, fileClient.downloadOptions()...)
+	if err != nil {
+		return err
+	}
+
+	var size int
+	for {
+		res, err := stream.Recv()
+		if err == io.EOF {
+			log.Printf("Downloaded archive of %d bytes", size)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		n, err := w.Write(res.GetChunk())
+		size += n
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (fileClient *FileClient) GetUsage(user *pb.Owner) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	res, err := fileClient.service.GetUsage(ctx, &pb.GetUsageRequest{User: user})
+	if err != nil {
+		log.Printf("Couldn't get storage usage: %v", err)
+		return
+	}
+
+	limit := "unlimited"
+	if res.GetLimitBytes() > 0 {
+		limit = fmt.Sprintf("%d bytes", res.GetLimitBytes())
+	}
+	fmt.Printf("Files: %d - Used: %d bytes - Limit: %s\n", res.GetFileCount(), res.GetUsedBytes(), limit)
+}
+
+func (fileClient *FileClient) ListVersions(id string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	res, err := fileClient.service.ListVersions(ctx, &pb.ListVersionsRequest{FileId: id})
+	if err != nil {
+		log.Printf("Couldn't list versions: %v", err)
+		return
+	}
+
+	log.Println("Versions:")
+	for _, file := range res.GetVersions() {
+		fmt.Printf("Version: %d - Name: %s - Size: %d - Date: %s\n", file.GetVersion(), file.GetTitle(), file.GetSize(), file.GetUpdatedAt().AsTime().UTC())
+	}
+}
+
+func (fileClient *FileClient) RestoreVersion(id string, version uint32) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	res, err := fileClient.service.RestoreVersion(ctx, &pb.RestoreVersionRequest{FileId: id, Version: version})
+	if err != nil {
+		log.Printf("Couldn't restore version: %v", err)
+		return
+	}
+
+	log.Printf("Restored version %d as version %d of file %s", version, res.GetFile().GetVersion(), res.GetFile().GetId())
+}
+
+func (fileClient *FileClient) PruneVersions(id string, keep uint32) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	res, err := fileClient.service.PruneVersions(ctx, &pb.PruneVersionsRequest{FileId: id, Keep: keep})
+	if err != nil {
+		log.Printf("Couldn't prune versions: %v", err)
+		return
+	}
+
+	log.Printf("Removed %d previous versions of file %s", res.GetRemoved(), id)
+}
+
+// UpdateMetadata changes the labels and tags of a file, paths are the fields to change as described in pb.UpdateMetadataRequest
+func (fileClient *FileClient) UpdateMetadata(id string, labels map[string]string, tags []string, paths []string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	res, err := fileClient.service.UpdateMetadata(ctx, &pb.UpdateMetadataRequest{
+		FileId:     id,
+		File:       &pb.File{Labels: labels, Tags: tags},
+		UpdateMask: &fieldmaskpb.FieldMask{Paths: paths},
+	})
+	if err != nil {
+		log.Printf("Couldn't update metadata: %v", err)
+		return
+	}
+
+	log.Printf("Updated metadata of file %s%s", res.GetFile().GetId(), formatLabels(res.GetFile().GetLabels(), res.GetFile().GetTags()))
+}
+
+// ShareFile gives the user access to the file, sharing it again changes the permission
+func (fileClient *FileClient) ShareFile(id string, user *pb.Owner, permission pb.Permission) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	res, err := fileClient.service.ShareFile(ctx, &pb.ShareFileRequest{FileId: id, User: user, Permission: permission})
+	if err != nil {
+		log.Printf("Couldn't share file: %v", err)
+		return
+	}
+
+	log.Printf("Shared file %s with %s for %s", res.GetFile().GetId(), user.GetName(), permission)
+}
+
+// UnshareFile revokes the access of the user to the file
+func (fileClient *FileClient) UnshareFile(id string, user *pb.Owner) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	res, err := fileClient.service.UnshareFile(ctx, &pb.UnshareFileRequest{FileId: id, User: user})
+	if err != nil {
+		log.Printf("Couldn't stop sharing file: %v", err)
+		return
+	}
+
+	log.Printf("Stopped sharing file %s with %s", res.GetFile().GetId(), user.GetName())
+}
+
+// GetFile prints the metadata of a file
+func (fileClient *FileClient) GetFile(id string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	file, err := fileClient.service.GetFile(ctx, &pb.GetFileRequest{FileId: id})
+	if err != nil {
+		log.Printf("Couldn't get file: %v", err)
+		return
+	}
+
+	printFile(file)
+}
+
+// GetFiles prints the metadata of several files, nothing is printed if any of them can't be read
+func (fileClient *FileClient) GetFiles(ids []string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	res, err := fileClient.service.BatchGetFiles(ctx, &pb.BatchGetFilesRequest{FileIds: ids})
+	if err != nil {
+		log.Printf("Couldn't get files: %v", err)
+		return
+	}
+
+	for _, file := range res.GetFiles() {
+		printFile(file)
+	}
+}
+
+// printFile prints all metadata of a file
+func printFile(file *pb.File) {
+	fmt.Printf("ID: %s - Name: %s - Owner: %s - Size: %d - Type: %s - Version: %d - Checksum: %s - Created: %s - Updated: %s%s\n",
+		file.GetId(), fullPath(file), file.GetOwner().GetName(), file.GetSize(), file.GetContentType(), file.GetVersion(),
+		file.GetChecksum(), file.GetCreatedAt().AsTime().UTC(), file.GetUpdatedAt().AsTime().UTC(),
+		formatLabels(file.GetLabels(), file.GetTags()))
+}
+
+// CreateShareLink prints a token anyone can download the file with until it expires,
+// maxDownloads 0 means unlimited and an empty password means none
+func (fileClient *FileClient) CreateShareLink(id string, expiresIn time.Duration, maxDownloads uint32, password string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	req := &pb.CreateShareLinkRequest{FileId: id, MaxDownloads: maxDownloads, Password: password}
+	if expiresIn > 0 {
+		req.ExpiresIn = durationpb.New(expiresIn)
+	}
+	link, err := fileClient.service.CreateShareLink(ctx, req)
+	if err != nil {
+		log.Printf("Couldn't create share link: %v", err)
+		return
+	}
+
+	log.Printf("Created share link %s expiring at %s, download the file with -option get-shared -link %s",
+		link.GetLinkId(), link.GetExpiresAt().AsTime().UTC(), link.GetToken())
+}
+
+// ListShareLinks lists the share links of the files of the user, only of the file if id isn't empty
+func (fileClient *FileClient) ListShareLinks(user *pb.Owner, id string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	res, err := fileClient.service.ListShareLinks(ctx, &pb.ListShareLinksRequest{Owner: user, FileId: id})
+	if err != nil {
+		log.Printf("Couldn't list share links: %v", err)
+		return
+	}
+
+	log.Println("Your share links:")
+	for _, link := range res.GetLinks() {
+		downloads := fmt.Sprintf("%d", link.GetDownloads())
+		if link.GetMaxDownloads() > 0 {
+			downloads += fmt.Sprintf("/%d", link.GetMaxDownloads())
+		}
+		fmt.Printf("ID: %s - File: %s - Expires: %s - Downloads: %s - Password: %t\n", link.GetLinkId(), link.GetFileId(),
+			link.GetExpiresAt().AsTime().UTC(), downloads, link.GetHasPassword())
+	}
+}
+
+// RevokeShareLink stops the share link from working
+func (fileClient *FileClient) RevokeShareLink(id string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	res, err := fileClient.service.RevokeShareLink(ctx, &pb.RevokeShareLinkRequest{LinkId: id})
+	if err != nil {
+		log.Printf("Couldn't revoke share link: %v", err)
+		return
+	}
+
+	log.Printf("Revoked share link %s to file %s", res.GetLink().GetLinkId(), res.GetLink().GetFileId())
+}
+
+// Delete moves the file to the trash, or removes it for good if permanent is set
+func (fileClient *FileClient) Delete(id string, permanent bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	res, err := fileClient.service.Delete(ctx, &pb.DeleteFileRequest{FileId: id, Permanent: permanent})
+	if err != nil {
+		log.Printf("Couldn't delete file: %v", err)
+		return
+	}
+
+	log.Printf("Successfully deleted file with id: %s and name: %s", res.GetFile().GetId(), res.GetFile().GetTitle())
+}
+
+func (fileClient *FileClient) ListTrash(user *pb.Owner) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream, err := fileClient.service.ListTrash(ctx, &pb.ListTrashRequest{User: user})
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	log.Println("Your trash:")
+	for {
+		res, err := stream.Recv()
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			log.Println("Cannot receive response from server: ", err)
+			return
+		}
+		file := res.GetFile()
+		fmt.Printf("ID: %s - Name: %s - Deleted: %s\n", file.GetId(), file.GetTitle(), file.GetDeletedAt().AsTime().UTC())
+	}
+}
+
+func (fileClient *FileClient) RestoreFromTrash(id string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	res, err := fileClient.service.RestoreFromTrash(ctx, &pb.RestoreFromTrashRequest{FileId: id})
+	if err != nil {
+		log.Printf("Couldn't restore file: %v", err)
+		return
+	}
+
+	log.Printf("Restored file with id: %s and name: %s", res.GetFile().GetId(), res.GetFile().GetTitle())
+}
+
+func (fileClient *FileClient) EmptyTrash(user *pb.Owner) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	res, err := fileClient.service.EmptyTrash(ctx, &pb.EmptyTrashRequest{User: user})
+	if err != nil {
+		log.Printf("Couldn't empty the trash: %v", err)
+		return
+	}
+
+	log.Printf("Removed %d files from the trash", res.GetRemoved())
+}
+
+// fileChecksum returns the hex encoded SHA-256 of the file and rewinds it
+func fileChecksum(file *os.File) (string, error) {
+	hash := sha256.New()
+	_, err := io.Copy(hash, file)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = file.Seek(0, io.SeekStart)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// formatLabels formats labels and tags to be appended to a line describing a file
+func formatLabels(labels map[string]string, tags []string) string {
+	var text strings.Builder
+	if len(labels) > 0 {
+		keys := make([]string, 0, len(labels))
+		for key := range labels {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		text.WriteString(" - Labels: ")
+		for i, key := range keys {
+			if i > 0 {
+				text.WriteString(", ")
+			}
+			text.WriteString(key + "=" + labels[key])
+		}
+	}
+	if len(tags) > 0 {
+		text.WriteString(" - Tags: " + strings.Join(tags, ", "))
+	}
+	return text.String()
 }
 
-func copyFromResponse(w *io.PipeWriter, stream pb.FileService_DownloadClient) {
+func copyFromResponse(w *io.PipeWriter, stream grpc.ClientStream) {
 	var err error
 	res := new(pb.DownloadFileResponse)
 	for {
@@ -233,6 +1314,7 @@ func copyFromResponse(w *io.PipeWriter, stream pb.FileService_DownloadClient) {
 		}
 		if err != nil {
 			log.Println("cannot receive stream response: ", err)
+			_ = w.CloseWithError(err)
 			return
 		}
 		if len(res.GetChunk()) > 0 {
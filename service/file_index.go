@@ -0,0 +1,298 @@
+package service
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/Nextasy01/grpc-file-service/pb"
+)
+
+// fileIndex finds the candidates of a search without looking at every file of the store.
+// Files are indexed by owner, name, media type, labels and the users they are shared with, and ordered by size and dates for range queries.
+// The caller synchronizes the access
+type fileIndex struct {
+	owners     map[string]idSet // owner name -> files
+	names      map[string]idSet // every substring of up to nameGramSize bytes of the lowercase title -> files
+	mediaTypes map[string]idSet // media type and its top-level type, e.g. "image/png" and "image/" -> files
+	labels     map[string]idSet // "key=value" and "key" -> files
+	grantees   map[string]idSet // name of a user the file is shared with -> files
+	sizes      sortedIndex
+	created    sortedIndex
+	updated    sortedIndex
+}
+
+// idSet is a set of file IDs
+type idSet map[string]struct{}
+
+// longest substrings of titles in the name index, longer parts of names are looked up by their substrings of this size
+const nameGramSize = 3
+
+func newFileIndex() *fileIndex {
+	return &fileIndex{
+		owners:     make(map[string]idSet),
+		names:      make(map[string]idSet),
+		mediaTypes: make(map[string]idSet),
+		labels:     make(map[string]idSet),
+		grantees:   make(map[string]idSet),
+	}
+}
+
+func (index *fileIndex) add(file *pb.File) {
+	id := file.GetId()
+	for _, key := range index.keys(file) {
+		set, ok := key.sets[key.name]
+		if !ok {
+			set = make(idSet)
+			key.sets[key.name] = set
+		}
+		set[id] = struct{}{}
+	}
+
+	index.sizes.add(int64(file.GetSize()), id)
+	index.created.add(file.GetCreatedAt().AsTime().UnixNano(), id)
+	index.updated.add(file.GetUpdatedAt().AsTime().UnixNano(), id)
+}
+
+func (index *fileIndex) remove(file *pb.File) {
+	id := file.GetId()
+	for _, key := range index.keys(file) {
+		delete(key.sets[key.name], id)
+		if len(key.sets[key.name]) == 0 {
+			delete(key.sets, key.name)
+		}
+	}
+
+	index.sizes.remove(int64(file.GetSize()), id)
+	index.created.remove(file.GetCreatedAt().AsTime().UnixNano(), id)
+	index.updated.remove(file.GetUpdatedAt().AsTime().UnixNano(), id)
+}
+
+// indexKey is an entry of one of the maps of the index
+type indexKey struct {
+	sets map[string]idSet
+	name string
+}
+
+// keys returns the entries of the maps of the index which refer to the file
+func (index *fileIndex) keys(file *pb.File) []indexKey {
+	keys := []indexKey{{index.owners, file.GetOwner().GetName()}}
+
+	for _, gram := range nameGrams(strings.ToLower(file.GetTitle())) {
+		keys = append(keys, indexKey{index.names, gram})
+	}
+
+	if mediaType := mediaTypeOf(file.GetContentType()); mediaType != "" {
+		topLevel, _, _ := strings.Cut(mediaType, "/")
+		keys = append(keys, indexKey{index.mediaTypes, mediaType}, indexKey{index.mediaTypes, topLevel + "/"})
+	}
+
+	for key, value := range file.GetLabels() {
+		keys = append(keys, indexKey{index.labels, labelIndexKey(key, value)}, indexKey{index.labels, labelIndexKey(key, "")})
+	}
+
+	for _, grant := range file.GetGrants() {
+		keys = append(keys, indexKey{index.grantees, grant.GetUser().GetName()})
+	}
+	return keys
+}
+
+// candidates returns the IDs of the files which may match the query, picked from the most selective index.
+// ok is false if no index applies to the query
+func (index *fileIndex) candidates(query FileQuery) (ids []string, ok bool) {
+	sets := make([]idSet, 0)
+	if query.Owner != "" {
+		sets = append(sets, index.owners[query.Owner])
+	}
+	// the name has to contain every literal part of the pattern
+	for _, literal := range nameLiterals(query.Name) {
+		size := nameGramSize
+		if len(literal) < size {
+			size = len(literal)
+		}
+		for i := 0; i+size <= len(literal); i++ {
+			sets = append(sets, index.names[literal[i:i+size]])
+		}
+	}
+	if query.ContentType != "" {
+		sets = append(sets, index.mediaTypes[queryMediaType(query.ContentType)])
+	}
+	for key, value := range query.Labels {
+		sets = append(sets, index.labels[labelIndexKey(key, value)])
+	}
+	if query.SharedWith != "" {
+		sets = append(sets, index.grantees[query.SharedWith])
+	}
+
+	ranges := make([][]indexEntry, 0)
+	if query.MinSize > 0 || query.MaxSize > 0 {
+		max := int64(maxFileSize)
+		if query.MaxSize > 0 && query.MaxSize < maxFileSize {
+			max = int64(query.MaxSize)
+		}
+		ranges = append(ranges, index.sizes.between(int64(query.MinSize), max))
+	}
+	if !query.Created.IsZero() {
+		ranges = append(ranges, index.created.between(query.Created.nanos()))
+	}
+	if !query.Updated.IsZero() {
+		ranges = append(ranges, index.updated.between(query.Updated.nanos()))
+	}
+
+	if len(sets) == 0 && len(ranges) == 0 {
+		return nil, false
+	}
+
+	var smallestSet idSet
+	var smallestRange []indexEntry
+	size := -1
+	for _, set := range sets {
+		if size < 0 || len(set) < size {
+			smallestSet, size = set, len(set)
+		}
+	}
+	for _, entries := range ranges {
+		if size < 0 || len(entries) < size {
+			smallestSet, smallestRange, size = nil, entries, len(entries)
+		}
+	}
+
+	ids = make([]string, 0, size)
+	for id := range smallestSet {
+		ids = append(ids, id)
+	}
+	for _, entry := range smallestRange {
+		ids = append(ids, entry.id)
+	}
+	return ids, true
+}
+
+// nameGrams returns the distinct substrings of the name up to nameGramSize bytes
+func nameGrams(name string) []string {
+	seen := make(map[string]bool)
+	grams := make([]string, 0)
+	for size := 1; size <= nameGramSize; size++ {
+		for i := 0; i+size <= len(name); i++ {
+			gram := name[i : i+size]
+			if !seen[gram] {
+				seen[gram] = true
+				grams = append(grams, gram)
+			}
+		}
+	}
+	return grams
+}
+
+// nameLiterals returns the lowercase parts of a name pattern which matching names contain as they are,
+// the parts between the wildcards of a glob pattern or the whole substring
+func nameLiterals(pattern string) []string {
+	pattern = strings.ToLower(pattern)
+	if !strings.ContainsAny(pattern, "*?[") {
+		if pattern == "" {
+			return nil
+		}
+		return []string{pattern}
+	}
+
+	literals := make([]string, 0)
+	var literal strings.Builder
+	end := func() {
+		if literal.Len() > 0 {
+			literals = append(literals, literal.String())
+			literal.Reset()
+		}
+	}
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '*', '?':
+			end()
+		case '[':
+			end()
+			// skips the character class, a closing bracket right after the opening one belongs to the class
+			j := i + 1
+			if j < len(pattern) && pattern[j] == '^' {
+				j++
+			}
+			if j < len(pattern) && pattern[j] == ']' {
+				j++
+			}
+			for j < len(pattern) && pattern[j] != ']' {
+				if pattern[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			i = j
+		case '\\':
+			if i+1 < len(pattern) {
+				i++
+				literal.WriteByte(pattern[i])
+			}
+		default:
+			literal.WriteByte(pattern[i])
+		}
+	}
+	end()
+	return literals
+}
+
+// labelIndexKey returns the key of a label in the index, an empty value stands for any value
+func labelIndexKey(key, value string) string {
+	if value == "" {
+		return key
+	}
+	return key + "=" + value
+}
+
+// mediaTypeOf returns the media type of a content type without parameters
+func mediaTypeOf(contentType string) string {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	return strings.ToLower(strings.TrimSpace(mediaType))
+}
+
+// queryMediaType returns the key of the media type to search for, a type without subtype is a top-level type
+func queryMediaType(contentType string) string {
+	mediaType := mediaTypeOf(contentType)
+	if !strings.Contains(mediaType, "/") {
+		mediaType += "/"
+	}
+	return mediaType
+}
+
+// sortedIndex orders files by a value, files with the same value by their ID
+type sortedIndex []indexEntry
+
+type indexEntry struct {
+	value int64
+	id    string
+}
+
+// position returns where the entry is or would be inserted
+func (index sortedIndex) position(value int64, id string) int {
+	return sort.Search(len(index), func(i int) bool {
+		return index[i].value > value || index[i].value == value && index[i].id >= id
+	})
+}
+
+func (index *sortedIndex) add(value int64, id string) {
+	i := index.position(value, id)
+	*index = append(*index, indexEntry{})
+	copy((*index)[i+1:], (*index)[i:])
+	(*index)[i] = indexEntry{value, id}
+}
+
+func (index *sortedIndex) remove(value int64, id string) {
+	i := index.position(value, id)
+	if i < len(*index) && (*index)[i] == (indexEntry{value, id}) {
+		*index = append((*index)[:i], (*index)[i+1:]...)
+	}
+}
+
+// between returns the entries with values from min up to and including max
+func (index sortedIndex) between(min, max int64) []indexEntry {
+	from := sort.Search(len(index), func(i int) bool { return index[i].value >= min })
+	to := sort.Search(len(index), func(i int) bool { return index[i].value > max })
+	if to < from {
+		return nil
+	}
+	return index[from:to]
+}
@@ -0,0 +1,62 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/Nextasy01/grpc-file-service/pb"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileIndexNames(t *testing.T) {
+	t.Parallel()
+
+	index := newFileIndex()
+	files := map[string]*pb.File{
+		"build": {Id: "build", Title: "Build-42.LOG"},
+		"log":   {Id: "log", Title: "build.log"},
+		"csv":   {Id: "csv", Title: "report.csv"},
+		"a":     {Id: "a", Title: "a"},
+	}
+	for _, file := range files {
+		index.add(file)
+	}
+
+	testCases := []struct {
+		name  string
+		query string
+		ids   []string
+	}{
+		{"substring", "port", []string{"csv"}},
+		{"short substring", "A", []string{"a"}},
+		{"glob", "*.log", []string{"build", "log"}},
+		{"glob with wildcards", "b?ild*4*", []string{"build"}},
+		{"character class", "[br]e*", []string{"csv"}},
+		{"escaped wildcard", "\\*", nil},
+		{"no match", "missing", nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// the candidates come from the index instead of every file of the store
+			ids, ok := index.candidates(FileQuery{Name: tc.query})
+			require.True(t, ok)
+
+			matched := make([]string, 0)
+			for _, id := range ids {
+				if (FileQuery{Name: tc.query}).matches(files[id]) {
+					matched = append(matched, id)
+				}
+			}
+			require.ElementsMatch(t, tc.ids, matched)
+		})
+	}
+
+	// a pattern without literal parts matches every name
+	_, ok := index.candidates(FileQuery{Name: "*"})
+	require.False(t, ok)
+
+	index.remove(files["csv"])
+	ids, ok := index.candidates(FileQuery{Name: "port"})
+	require.True(t, ok)
+	require.Empty(t, ids)
+}
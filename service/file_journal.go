@@ -0,0 +1,358 @@
+package service
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/Nextasy01/grpc-file-service/pb"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+const (
+	journalPut    = "put"
+	journalDelete = "delete"
+	journalPrune  = "prune"
+	journalMkdir  = "mkdir"
+	journalMove   = "move"
+	journalRmdir  = "rmdir"
+	journalUpload = "upload"
+	journalEnd    = "end"
+)
+
+// journalEntry is a single line of the metadata journal
+type journalEntry struct {
+	Op      string          `json:"op"`
+	ID      string          `json:"id,omitempty"`
+	File    json.RawMessage `json:"file,omitempty"`
+	Blob    string          `json:"blob,omitempty"`
+	Archive bool            `json:"archive,omitempty"` // the replaced file is kept as a previous version
+	Version uint32          `json:"version,omitempty"`
+	Folder  json.RawMessage `json:"folder,omitempty"`
+	Owner   string          `json:"owner,omitempty"`
+	Path    string          `json:"path,omitempty"`
+	To      string          `json:"to,omitempty"`      // destination of a moved folder
+	Offset  uint64          `json:"offset,omitempty"`  // bytes of a resumable upload durably written
+	Replace string          `json:"replace,omitempty"` // ID of the file a resumable upload saves a new version of
+}
+
+// journalState is the metadata rebuilt from the journal
+type journalState struct {
+	files    map[string]*pb.File
+	blobs    map[string]string        // file ID -> blob of the current version
+	versions map[string][]fileVersion // file ID -> previous versions, oldest first
+	folders  map[string]*pb.Folder    // see folderKey
+	uploads  map[string]journalEntry  // session ID -> latest progress of a resumable upload
+}
+
+// fileJournal is an append-only log of file metadata changes.
+// Every entry is written as one JSON line and synced to disk before the change becomes visible
+type fileJournal struct {
+	mutex sync.Mutex
+	path  string
+	file  *os.File
+}
+
+// openFileJournal replays the journal at path and returns the resulting metadata.
+// A torn last line (e.g. after a crash in the middle of a write) is discarded
+func openFileJournal(path string) (*fileJournal, *journalState, error) {
+	state := &journalState{
+		files:    make(map[string]*pb.File),
+		blobs:    make(map[string]string),
+		versions: make(map[string][]fileVersion),
+		folders:  make(map[string]*pb.Folder),
+		uploads:  make(map[string]journalEntry),
+	}
+
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	reader := bufio.NewReader(f)
+	var offset int64
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			if len(line) > 0 {
+				// the last entry was not completely written
+				if err := f.Truncate(offset); err != nil {
+					f.Close()
+					return nil, nil, err
+				}
+			}
+			break
+		}
+		if err != nil {
+			f.Close()
+			return nil, nil, err
+		}
+
+		if err := state.apply(line); err != nil {
+			f.Close()
+			return nil, nil, fmt.Errorf("corrupted journal at offset %d: %w", offset, err)
+		}
+		offset += int64(len(line))
+	}
+
+	if _, err := f.Seek(0, io.SeekEnd); err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+
+	return &fileJournal{path: path, file: f}, state, nil
+}
+
+func (state *journalState) apply(line []byte) error {
+	var entry journalEntry
+	if err := json.Unmarshal(line, &entry); err != nil {
+		return err
+	}
+
+	switch entry.Op {
+	case journalPut:
+		file := &pb.File{}
+		if err := protojson.Unmarshal(entry.File, file); err != nil {
+			return err
+		}
+		id := file.GetId()
+		if current, ok := state.files[id]; ok && entry.Archive {
+			state.versions[id] = append(state.versions[id], fileVersion{current, state.blobs[id]})
+		}
+		state.files[id] = file
+		if entry.Blob != "" {
+			state.blobs[id] = entry.Blob
+		}
+	case journalDelete:
+		delete(state.files, entry.ID)
+		delete(state.blobs, entry.ID)
+		delete(state.versions, entry.ID)
+	case journalPrune:
+		versions := state.versions[entry.ID]
+		for i, version := range versions {
+			if version.file.GetVersion() == entry.Version {
+				state.versions[entry.ID] = append(versions[:i:i], versions[i+1:]...)
+				break
+			}
+		}
+	case journalMkdir:
+		folder := &pb.Folder{}
+		if err := protojson.Unmarshal(entry.Folder, folder); err != nil {
+			return err
+		}
+		state.folders[folderKey(folder.GetOwner().GetName(), folder.GetPath())] = folder
+	case journalMove:
+		moveTree(state.files, state.versions, state.folders, entry.Owner, entry.Path, entry.To)
+	case journalRmdir:
+		removeFolders(state.folders, entry.Owner, entry.Path)
+	case journalUpload:
+		state.uploads[entry.ID] = entry
+	case journalEnd:
+		delete(state.uploads, entry.ID)
+	default:
+		return fmt.Errorf("unknown journal operation %q", entry.Op)
+	}
+	return nil
+}
+
+// put records the current state of a file and the blob with its contents.
+// With archive the previous state is kept as an older version of the file
+func (journal *fileJournal) put(file *pb.File, blob string, archive bool) error {
+	entry, err := putEntry(file, blob, archive)
+	if err != nil {
+		return err
+	}
+	return journal.append(entry)
+}
+
+// delete records that a file was removed with all its versions
+func (journal *fileJournal) delete(id string) error {
+	return journal.append(journalEntry{Op: journalDelete, ID: id})
+}
+
+// prune records that a previous version of a file was removed
+func (journal *fileJournal) prune(id string, version uint32) error {
+	return journal.append(journalEntry{Op: journalPrune, ID: id, Version: version})
+}
+
+// mkdir records that a folder was created
+func (journal *fileJournal) mkdir(folder *pb.Folder) error {
+	entry, err := mkdirEntry(folder)
+	if err != nil {
+		return err
+	}
+	return journal.append(entry)
+}
+
+// move records that a folder was moved with all files and folders in it
+func (journal *fileJournal) move(owner, from, to string) error {
+	return journal.append(journalEntry{Op: journalMove, Owner: owner, Path: from, To: to})
+}
+
+// rmdir records that a folder was removed with all folders in it
+func (journal *fileJournal) rmdir(owner, path string) error {
+	return journal.append(journalEntry{Op: journalRmdir, Owner: owner, Path: path})
+}
+
+// upload records the progress of a resumable upload writing the contents of file to the temporary file blob
+func (journal *fileJournal) upload(id string, file *pb.File, blob, replaces string, offset uint64) error {
+	entry, err := uploadEntry(id, file, blob, replaces, offset)
+	if err != nil {
+		return err
+	}
+	return journal.append(entry)
+}
+
+// end records that a resumable upload was finished or abandoned
+func (journal *fileJournal) end(id string) error {
+	return journal.append(journalEntry{Op: journalEnd, ID: id})
+}
+
+func uploadEntry(id string, file *pb.File, blob, replaces string, offset uint64) (journalEntry, error) {
+	data, err := protojson.Marshal(file)
+	if err != nil {
+		return journalEntry{}, err
+	}
+	return journalEntry{Op: journalUpload, ID: id, File: data, Blob: blob, Replace: replaces, Offset: offset}, nil
+}
+
+func mkdirEntry(folder *pb.Folder) (journalEntry, error) {
+	data, err := protojson.Marshal(folder)
+	if err != nil {
+		return journalEntry{}, err
+	}
+	return journalEntry{Op: journalMkdir, Folder: data}, nil
+}
+
+func putEntry(file *pb.File, blob string, archive bool) (journalEntry, error) {
+	data, err := protojson.Marshal(file)
+	if err != nil {
+		return journalEntry{}, err
+	}
+	return journalEntry{Op: journalPut, File: data, Blob: blob, Archive: archive}, nil
+}
+
+func (journal *fileJournal) append(entry journalEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	journal.mutex.Lock()
+	defer journal.mutex.Unlock()
+
+	if journal.file == nil {
+		return errors.New("journal is closed")
+	}
+
+	if _, err := journal.file.Write(line); err != nil {
+		return err
+	}
+	return journal.file.Sync()
+}
+
+// compact rewrites the journal so it only contains the given state
+func (journal *fileJournal) compact(state *journalState) error {
+	journal.mutex.Lock()
+	defer journal.mutex.Unlock()
+
+	tmpPath := journal.path + ".tmp"
+	tmp, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+
+	writer := bufio.NewWriter(tmp)
+	writeEntry := func(entry journalEntry) error {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		_, err = writer.Write(append(line, '\n'))
+		return err
+	}
+	write := func(file *pb.File, blob string, archive bool) error {
+		entry, err := putEntry(file, blob, archive)
+		if err != nil {
+			return err
+		}
+		return writeEntry(entry)
+	}
+
+	for _, folder := range state.folders {
+		entry, err := mkdirEntry(folder)
+		if err == nil {
+			err = writeEntry(entry)
+		}
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmpPath)
+			return err
+		}
+	}
+
+	for _, entry := range state.uploads {
+		err = writeEntry(entry)
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmpPath)
+			return err
+		}
+	}
+
+	for id, file := range state.files {
+		// previous versions are replayed in order, each one archived by the next
+		versions := state.versions[id]
+		for i, version := range versions {
+			err = write(version.file, version.blob, i > 0)
+			if err != nil {
+				break
+			}
+		}
+		if err == nil {
+			err = write(file, state.blobs[id], len(versions) > 0)
+		}
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmpPath)
+			return err
+		}
+	}
+
+	if err := writer.Flush(); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Rename(tmpPath, journal.path); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+
+	journal.file.Close()
+	journal.file = tmp
+	return nil
+}
+
+func (journal *fileJournal) close() error {
+	journal.mutex.Lock()
+	defer journal.mutex.Unlock()
+
+	if journal.file == nil {
+		return nil
+	}
+	err := journal.file.Close()
+	journal.file = nil
+	return err
+}
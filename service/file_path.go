@@ -0,0 +1,47 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/Nextasy01/grpc-file-service/pb"
+)
+
+// ErrInvalidPath is returned for paths pointing outside of the root of a user
+var ErrInvalidPath = errors.New("invalid path")
+
+// cleanDirectory normalizes the directory of a file to a relative, slash separated path
+func cleanDirectory(dir string) (string, error) {
+	dir = strings.ReplaceAll(dir, "\\", "/")
+	if dir == "" {
+		return "", nil
+	}
+	if strings.HasPrefix(dir, "/") {
+		return "", fmt.Errorf("%w %q: must be relative", ErrInvalidPath, dir)
+	}
+
+	dir = path.Clean(dir)
+	if dir == "." {
+		return "", nil
+	}
+	if dir == ".." || strings.HasPrefix(dir, "../") {
+		return "", fmt.Errorf("%w %q: must not leave the root", ErrInvalidPath, dir)
+	}
+	return dir, nil
+}
+
+// fullPath returns the path of a file relative to the root of its owner
+func fullPath(file *pb.File) string {
+	return path.Join(file.GetPath(), file.GetTitle())
+}
+
+// parentFolder returns the folder containing dir, the root is empty
+func parentFolder(dir string) string {
+	parent := path.Dir(dir)
+	if parent == "." {
+		return ""
+	}
+	return parent
+}
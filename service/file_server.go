@@ -2,21 +2,32 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log"
-	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync/atomic"
 
 	"github.com/Nextasy01/grpc-file-service/pb"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 )
 
 const maxFileSize = 1 << 30 // gigabyte
-const maxChunkSize = 1024
+
+// chunk sizes used unless the server is configured otherwise
+const (
+	DefaultChunkSize    = 64 << 10
+	DefaultMaxChunkSize = 1 << 20
+)
+
+// MessageOverhead is room for the fields sent along with a chunk of the maximum size
+const MessageOverhead = 64 << 10
 
 const (
 	uploadLimit   = 10
@@ -28,13 +39,54 @@ const (
 type FileServer struct {
 	pb.UnimplementedFileServiceServer
 	fileStore            FileStore
+	uploadSessions       *UploadSessionStore
+	multipartUploads     *MultipartUploadStore
+	quotas               *QuotaManager
+	shareLinks           *ShareLinkStore
+	linkSigner           *JWTManager
+	versioning           bool
+	chunkSize            int
+	maxChunkSize         int
 	requestUploadCount   atomic.Int32
 	requestDownloadCount atomic.Int32
 	requestListCount     atomic.Int32
 }
 
-func NewFileServer(fileStore FileStore) *FileServer {
-	return &FileServer{fileStore: fileStore}
+func NewFileServer(fileStore FileStore, uploadSessions *UploadSessionStore, multipartUploads *MultipartUploadStore, quotas *QuotaManager) *FileServer {
+	return &FileServer{
+		fileStore:        fileStore,
+		uploadSessions:   uploadSessions,
+		multipartUploads: multipartUploads,
+		quotas:           quotas,
+		chunkSize:        DefaultChunkSize,
+		maxChunkSize:     DefaultMaxChunkSize,
+	}
+}
+
+// Close stops the background work of the stores of the server
+func (server *FileServer) Close() {
+	server.uploadSessions.Close()
+	server.multipartUploads.Close()
+	if server.shareLinks != nil {
+		server.shareLinks.Close()
+	}
+}
+
+// SetChunkSizes sets the chunk size the server prefers and the largest chunk it accepts
+func (server *FileServer) SetChunkSizes(preferred, max int) error {
+	if preferred <= 0 || preferred > max {
+		return fmt.Errorf("invalid chunk sizes: preferred %d, maximum %d", preferred, max)
+	}
+
+	server.chunkSize = preferred
+	server.maxChunkSize = max
+	return nil
+}
+
+// EnableVersioning makes uploads of an existing file, identified by its ID or by
+// the same owner and title, save a new version instead of creating another file
+func (server *FileServer) EnableVersioning() {
+	server.versioning = true
 }
 
 // Return list of uploaded files of client
@@ -51,16 +103,107 @@ func (server *FileServer) List(req *pb.ListFilesRequest, stream pb.FileService_L
 	}
 	log.Println("Returning list of uploaded files for user: ", req.GetUser().GetName())
 
-	files := server.fileStore.List(req.GetUser().GetName())
+	err := authorizeUser(stream.Context(), req.GetUser().GetName())
+	if err != nil {
+		return err
+	}
+
+	dir, err := cleanDirectory(req.GetFolder())
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	listed := func(path string) bool {
+		if req.GetChildrenOnly() {
+			return path == dir
+		}
+		return inFolder(path, dir)
+	}
 
-	for _, file := range files {
-		err := contextError(stream.Context())
+	order, err := parseOrder(req.GetOrderBy())
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	if req.GetPageSize() < 0 {
+		return status.Errorf(codes.InvalidArgument, "page size %d is negative", req.GetPageSize())
+	}
+	pageSize := int(req.GetPageSize())
+	if pageSize > maxPageSize {
+		pageSize = maxPageSize
+	}
+
+	// the token has to come from a listing of the same files in the same order
+	token := pageToken{
+		Owner:    req.GetUser().GetName(),
+		Folder:   dir,
+		Children: req.GetChildrenOnly(),
+		Shared:   req.GetSharedWithMe(),
+		Order:    order.String(),
+	}
+	var after *sortKey
+	if req.GetPageToken() != "" {
+		previous, err := decodePageToken(req.GetPageToken())
 		if err != nil {
-			return err
+			return status.Error(codes.InvalidArgument, err.Error())
+		}
+		token.After = previous.After
+		if previous != token {
+			return status.Error(codes.InvalidArgument, "the page token belongs to a listing of other files or in another order")
+		}
+		after = &previous.After
+	}
+
+	responses := make([]*pb.ListFilesResponse, 0)
+	if req.GetIncludeFolders() && !req.GetSharedWithMe() && after == nil {
+		for _, folder := range server.fileStore.Folders(req.GetUser().GetName()) {
+			if folder.GetPath() != dir && listed(parentFolder(folder.GetPath())) {
+				responses = append(responses, &pb.ListFilesResponse{Folder: folder})
+			}
+		}
+	}
+
+	type listedFile struct {
+		file *pb.File
+		key  sortKey
+	}
+	stored := server.fileStore.List(req.GetUser().GetName())
+	if req.GetSharedWithMe() {
+		stored = server.fileStore.Search(FileQuery{SharedWith: req.GetUser().GetName()})
+	}
+	files := make([]listedFile, 0)
+	for _, file := range stored {
+		if listed(file.GetPath()) {
+			files = append(files, listedFile{file, order.key(file)})
+		}
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return order.less(files[i].key, files[j].key)
+	})
+
+	if after != nil {
+		start := sort.Search(len(files), func(i int) bool {
+			return order.less(*after, files[i].key)
+		})
+		files = files[start:]
+	}
+	var nextPageToken string
+	if pageSize > 0 && len(files) > pageSize {
+		files = files[:pageSize]
+		token.After = files[pageSize-1].key
+		nextPageToken = token.encode()
+	}
+
+	for i, entry := range files {
+		res := &pb.ListFilesResponse{File: hideGrants(stream.Context(), entry.file)}
+		if i == len(files)-1 {
+			res.NextPageToken = nextPageToken
 		}
+		responses = append(responses, res)
+	}
 
-		res := &pb.ListFilesResponse{
-			File: file,
+	for _, res := range responses {
+		err := contextError(stream.Context())
+		if err != nil {
+			return err
 		}
 
 		err = stream.Send(res)
@@ -86,6 +229,12 @@ func (server *FileServer) Upload(stream pb.FileService_UploadServer) error {
 			break
 		}
 	}
+
+	err := server.advertiseChunkSizes(stream)
+	if err != nil {
+		return err
+	}
+
 	req, err := stream.Recv()
 	if err != nil {
 		return err
@@ -95,11 +244,18 @@ func (server *FileServer) Upload(stream pb.FileService_UploadServer) error {
 
 	log.Printf("Received request to upload file - %s", fullName)
 
-	file := NewFile()
+	writer, err := server.createWriter(req.GetFile())
+	if err != nil {
+		return err
+	}
+	defer writer.Abort() // no-op once the file is committed
 
 	var fileSize uint64
 	fileSize = 0
 
+	owner := req.GetFile().GetOwner().GetName()
+	defer func() { server.quotas.Release(owner, fileSize) }()
+
 	for {
 		err := contextError(stream.Context())
 		if err != nil {
@@ -120,27 +276,46 @@ func (server *FileServer) Upload(stream pb.FileService_UploadServer) error {
 		}
 
 		chunk := req.GetChunk()
-		fileSize += uint64(len(chunk))
 
 		log.Printf("Receive a chunk with size: %d", len(chunk))
 
-		if fileSize > maxFileSize {
+		err = server.checkChunk(chunk)
+		if err != nil {
+			return err
+		}
+
+		if fileSize+uint64(len(chunk)) > maxFileSize {
 			log.Println("The file size is too large")
 			return status.Errorf(codes.InvalidArgument,
 				"the file size is too large. Expected < %d bytes", maxFileSize)
 		}
 
-		_, err = file.buffer.Write(chunk)
+		err = server.quotas.Reserve(owner, uint64(len(chunk)))
+		if err != nil {
+			log.Printf("User %s is out of storage", owner)
+			return status.Errorf(codes.ResourceExhausted,
+				"storage quota of %d bytes is exceeded", server.quotas.Limit(owner))
+		}
+		fileSize += uint64(len(chunk))
+
+		_, err = writer.Write(chunk)
 		if err != nil {
 			log.Println("Cannot write a chunk of data", err)
-			return err
+			return status.Errorf(codes.Internal, "cannot write chunk data: %v", err)
 		}
 	}
 
-	err = server.fileStore.Save(req.GetFile(), *file.buffer)
+	checksum := req.GetFile().GetChecksum()
+	if checksum != "" && !strings.EqualFold(checksum, writer.Checksum()) {
+		log.Printf("Checksum mismatch for file - %s", fullName)
+		return status.Errorf(codes.DataLoss,
+			"checksum mismatch: expected %s, received data has %s", checksum, writer.Checksum())
+	}
+
+	err = writer.Commit()
 	if err != nil {
 		log.Println("Cannot save file to the store ", err)
-		return err
+		return status.Errorf(codes.Internal, "cannot save file: %v", err)
 	}
 
 	res := &pb.UploadFileResponse{
@@ -180,24 +355,70 @@ func (server *FileServer) Download(req *pb.DownloadFileRequest, stream pb.FileSe
 	if file == nil {
 		return status.Errorf(codes.NotFound, "file with id \"%s\" was not found", req.GetFileId())
 	}
-
-	err := stream.SendHeader(Metadata(file)) // we are sending file metadata to headers once
+	err := authorizeContents(stream.Context(), file)
 	if err != nil {
-		return status.Error(codes.Internal, "couldn't send file metadata")
+		return err
+	}
+
+	return server.sendFile(req, file, stream)
+}
+
+// downloadStream is a stream the contents of a file are sent on
+type downloadStream interface {
+	Send(*pb.DownloadFileResponse) error
+	grpc.ServerStream
+}
+
+// sendFile sends the range of the version of the file asked for in req
+func (server *FileServer) sendFile(req *pb.DownloadFileRequest, file *pb.File, stream downloadStream) error {
+	if req.GetVersion() != 0 {
+		file = findVersion(server.fileStore.Versions(req.GetFileId()), req.GetVersion())
+		if file == nil {
+			return status.Errorf(codes.NotFound, "version %d of file \"%s\" was not found", req.GetVersion(), req.GetFileId())
+		}
 	}
 
-	fileToSend := NewFile()
+	offset, length := req.GetOffset(), req.GetLength()
+	if offset > file.GetSize() {
+		return status.Errorf(codes.OutOfRange,
+			"offset %d is beyond the end of the file of %d bytes", offset, file.GetSize())
+	}
+	if length == 0 {
+		length = file.GetSize() - offset
+	}
+	if length > file.GetSize()-offset {
+		return status.Errorf(codes.OutOfRange,
+			"range of %d bytes from offset %d is beyond the end of the file of %d bytes", length, offset, file.GetSize())
+	}
 
-	fileToSend.Path = fmt.Sprintf("files/%s.%s", file.GetId(), strings.Split(file.GetTitle(), ".")[1])
+	chunkSize := server.chunkSize
+	if req.GetChunkSize() != 0 {
+		chunkSize = int(req.GetChunkSize())
+	}
+	if chunkSize > server.maxChunkSize {
+		chunkSize = server.maxChunkSize
+	}
 
-	f, err := os.Open(fileToSend.Path)
+	// we are sending file metadata to headers once
+	err := stream.SendHeader(metadata.Join(Metadata(file), ChunkSizeMetadata(chunkSize, server.maxChunkSize)))
 	if err != nil {
-		return err
+		return status.Error(codes.Internal, "couldn't send file metadata")
+	}
+
+	f, err := server.fileStore.OpenVersion(file.GetId(), req.GetVersion())
+	if err != nil {
+		return status.Errorf(codes.Internal, "cannot open file: %v", err)
 	}
 
 	defer f.Close()
 
-	res := &pb.DownloadFileResponse{Chunk: make([]byte, maxChunkSize)}
+	_, err = f.Seek(int64(offset), io.SeekStart)
+	if err != nil {
+		return status.Errorf(codes.Internal, "cannot seek file: %v", err)
+	}
+	reader := io.LimitReader(f, int64(length))
+
+	res := &pb.DownloadFileResponse{Chunk: make([]byte, chunkSize)}
 	var n int
 
 	for {
@@ -208,7 +429,7 @@ func (server *FileServer) Download(req *pb.DownloadFileRequest, stream pb.FileSe
 
 		log.Println("Sending data")
 
-		n, err = f.Read(res.Chunk)
+		n, err = reader.Read(res.Chunk[:chunkSize])
 		if err == io.EOF {
 			log.Println("No more data to send")
 			break
@@ -231,6 +452,130 @@ func (server *FileServer) Download(req *pb.DownloadFileRequest, stream pb.FileSe
 	return nil
 }
 
+// Deletes a file of the caller from the server
+// Moves a file to the trash, or removes it for good if the request asks for that
+func (server *FileServer) Delete(ctx context.Context, req *pb.DeleteFileRequest) (*pb.DeleteFileResponse, error) {
+	if !req.GetPermanent() {
+		return server.trash(ctx, req.GetFileId())
+	}
+
+	// files in the trash can be deleted permanently as well
+	file := server.fileStore.FindTrashed(req.GetFileId())
+	if file != nil {
+		err := authorizeOwner(ctx, file)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		_, err := server.findOwnedFile(ctx, req.GetFileId())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	file, err := server.fileStore.Delete(req.GetFileId())
+	if errors.Is(err, ErrNotFound) {
+		return nil, status.Errorf(codes.NotFound, "file with id \"%s\" was not found", req.GetFileId())
+	}
+	if err != nil {
+		log.Println("Cannot delete file from the store ", err)
+		return nil, status.Errorf(codes.Internal, "cannot delete file: %v", err)
+	}
+
+	log.Printf("Deleted file - %s", file.GetTitle())
+	return &pb.DeleteFileResponse{File: file}, nil
+}
+
+// Returns how much storage a user takes and how much is allowed
+func (server *FileServer) GetUsage(ctx context.Context, req *pb.GetUsageRequest) (*pb.GetUsageResponse, error) {
+	username := req.GetUser().GetName()
+	if username == "" {
+		return nil, status.Error(codes.InvalidArgument, "user name is required")
+	}
+
+	usage := server.fileStore.Usage(username)
+	return &pb.GetUsageResponse{
+		UsedBytes:  usage.Bytes,
+		FileCount:  usage.Files,
+		LimitBytes: server.quotas.Limit(username),
+	}, nil
+}
+
+// createWriter starts saving an uploaded file. With versioning enabled an existing file
+// with the same ID, or the same owner and title, gets a new version instead
+func (server *FileServer) createWriter(file *pb.File) (FileWriter, error) {
+	dir, err := cleanDirectory(file.GetPath())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	file.Path = dir
+	file.Title = filepath.Base(file.GetTitle())
+
+	err = checkMetadata(file)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	// access is only granted by the owner through ShareFile
+	file.Grants = nil
+
+	var existing *pb.File
+	if server.versioning {
+		existing = server.fileStore.Find(file.GetId())
+		if existing == nil {
+			existing = server.fileStore.FindByPath(file.GetOwner().GetName(), fullPath(file))
+		}
+	}
+
+	var writer FileWriter
+	if existing != nil {
+		if existing.GetOwner().GetName() != file.GetOwner().GetName() {
+			return nil, status.Error(codes.PermissionDenied, "only the owner of the file can upload a new version")
+		}
+		log.Printf("Saving a new version of file %s", existing.GetId())
+		writer, err = server.fileStore.CreateVersion(existing.GetId(), file)
+	} else {
+		writer, err = server.fileStore.Create(file)
+	}
+
+	if err != nil {
+		log.Println("Cannot create file in the store ", err)
+		return nil, status.Errorf(codes.Internal, "cannot create file: %v", err)
+	}
+	return writer, nil
+}
+
+// advertiseChunkSizes tells the client of an upload stream which chunk sizes to use
+func (server *FileServer) advertiseChunkSizes(stream grpc.ServerStream) error {
+	err := stream.SendHeader(ChunkSizeMetadata(server.chunkSize, server.maxChunkSize))
+	if err != nil {
+		return status.Errorf(codes.Internal, "couldn't send chunk sizes: %v", err)
+	}
+	return nil
+}
+
+// checkChunk rejects chunks larger than the maximum chunk size
+func (server *FileServer) checkChunk(chunk []byte) error {
+	if len(chunk) > server.maxChunkSize {
+		return status.Errorf(codes.InvalidArgument,
+			"chunk of %d bytes exceeds the maximum chunk size of %d bytes", len(chunk), server.maxChunkSize)
+	}
+	return nil
+}
+
+// authorizeOwner checks that the caller owns the file or is an admin
+func authorizeOwner(ctx context.Context, file *pb.File) error {
+	claims, ok := ClaimsFromContext(ctx)
+	if !ok {
+		return status.Error(codes.Unauthenticated, "caller is not authenticated")
+	}
+
+	if claims.Role == adminRole || claims.Username == file.GetOwner().GetName() {
+		return nil
+	}
+
+	return status.Error(codes.PermissionDenied, "only the owner of the file can do this")
+}
+
 // the numerous cases of context error
 func contextError(ctx context.Context) error {
 	switch ctx.Err() {
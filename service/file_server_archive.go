@@ -0,0 +1,269 @@
+package service
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/Nextasy01/grpc-file-service/pb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Streams the requested files as a single zip or tar.gz archive built on the fly
+func (server *FileServer) DownloadArchive(req *pb.DownloadArchiveRequest, stream pb.FileService_DownloadArchiveServer) error {
+	server.requestDownloadCount.Add(1) // incrementing concurent request count
+	defer server.requestDownloadCount.Add(-1)
+
+	for {
+		if server.requestDownloadCount.Load() > downloadLimit {
+			log.Printf("Download limit(%d) is exceeded. Waiting for other files finish downloading", downloadLimit)
+		} else {
+			break
+		}
+	}
+
+	files, err := server.archiveFiles(stream, req)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Sending archive of %d files", len(files))
+
+	sender := &chunkSender{stream: stream, buf: make([]byte, 0, server.chunkSize)}
+	var archive archiveWriter
+	switch req.GetFormat() {
+	case pb.ArchiveFormat_ZIP:
+		archive = newZipArchive(sender)
+	case pb.ArchiveFormat_TAR_GZ:
+		archive = newTarGzArchive(sender)
+	default:
+		return status.Errorf(codes.InvalidArgument, "unknown archive format %v", req.GetFormat())
+	}
+
+	names := make(map[string]bool)
+	for _, file := range files {
+		err := contextError(stream.Context())
+		if err != nil {
+			return err
+		}
+
+		err = server.addToArchive(archive, file, archiveName(names, file))
+		if err != nil {
+			log.Println("Cannot add file to the archive ", err)
+			return status.Errorf(codes.Internal, "cannot add file %s to the archive: %v", file.GetId(), err)
+		}
+	}
+
+	err = archive.Close()
+	if err == nil {
+		err = sender.Flush()
+	}
+	if err != nil {
+		log.Println("Cannot send the archive ", err)
+		return status.Errorf(codes.Internal, "cannot send the archive: %v", err)
+	}
+	return nil
+}
+
+// archiveFiles returns the requested files the caller may download, sorted by path
+func (server *FileServer) archiveFiles(stream pb.FileService_DownloadArchiveServer, req *pb.DownloadArchiveRequest) ([]*pb.File, error) {
+	ctx := stream.Context()
+	seen := make(map[string]bool)
+	files := make([]*pb.File, 0, len(req.GetFileIds()))
+
+	for _, id := range req.GetFileIds() {
+		if seen[id] {
+			continue
+		}
+		file := server.fileStore.Find(id)
+		if file == nil {
+			return nil, status.Errorf(codes.NotFound, "file with id \"%s\" was not found", id)
+		}
+		err := authorizeContents(ctx, file)
+		if err != nil {
+			return nil, err
+		}
+		seen[id] = true
+		files = append(files, file)
+	}
+
+	if req.GetOwner().GetName() != "" {
+		err := authorizeUser(ctx, req.GetOwner().GetName())
+		if err != nil {
+			return nil, err
+		}
+		dir, err := cleanDirectory(req.GetFolder())
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		// admins only get the files of other users which are shared with them
+		for _, file := range server.fileStore.List(req.GetOwner().GetName()) {
+			if !inFolder(file.GetPath(), dir) || !hasTags(file, req.GetTags()) {
+				continue
+			}
+			if !seen[file.GetId()] && authorizeContents(ctx, file) == nil {
+				seen[file.GetId()] = true
+				files = append(files, file)
+			}
+		}
+	}
+
+	if len(files) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "no files to archive")
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return fullPath(files[i]) < fullPath(files[j])
+	})
+	return files, nil
+}
+
+func (server *FileServer) addToArchive(archive archiveWriter, file *pb.File, name string) error {
+	contents, err := server.fileStore.Open(file.GetId())
+	if err != nil {
+		return err
+	}
+	defer contents.Close()
+
+	// the file may have got a new version since its metadata was read, the size of the opened contents is what gets written
+	size, err := contents.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	_, err = contents.Seek(0, io.SeekStart)
+	if err != nil {
+		return err
+	}
+
+	w, err := archive.Create(file, name, size)
+	if err != nil {
+		return err
+	}
+	_, err = io.CopyN(w, contents, size)
+	return err
+}
+
+// hasTags reports whether the file has every one of the tags
+func hasTags(file *pb.File, tags []string) bool {
+	for _, tag := range tags {
+		found := false
+		for _, fileTag := range file.GetTags() {
+			if fileTag == tag {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// archiveName returns the path of the file, numbered if another file in the archive has the same path
+func archiveName(names map[string]bool, file *pb.File) string {
+	name := fullPath(file)
+	base := strings.TrimSuffix(name, file.GetExtension())
+	for i := 2; names[name]; i++ {
+		name = fmt.Sprintf("%s (%d)%s", base, i, file.GetExtension())
+	}
+	names[name] = true
+	return name
+}
+
+// archiveWriter adds files to an archive written to a stream
+type archiveWriter interface {
+	// Create starts a new entry of size bytes, its contents are written to the returned writer
+	Create(file *pb.File, name string, size int64) (io.Writer, error)
+	// Close finishes the archive
+	Close() error
+}
+
+type zipArchive struct {
+	writer *zip.Writer
+}
+
+func newZipArchive(w io.Writer) *zipArchive {
+	return &zipArchive{writer: zip.NewWriter(w)}
+}
+
+func (archive *zipArchive) Create(file *pb.File, name string, size int64) (io.Writer, error) {
+	return archive.writer.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: file.GetUpdatedAt().AsTime(),
+	})
+}
+
+func (archive *zipArchive) Close() error {
+	return archive.writer.Close()
+}
+
+type tarGzArchive struct {
+	gzip   *gzip.Writer
+	writer *tar.Writer
+}
+
+func newTarGzArchive(w io.Writer) *tarGzArchive {
+	compressed := gzip.NewWriter(w)
+	return &tarGzArchive{gzip: compressed, writer: tar.NewWriter(compressed)}
+}
+
+func (archive *tarGzArchive) Create(file *pb.File, name string, size int64) (io.Writer, error) {
+	err := archive.writer.WriteHeader(&tar.Header{
+		Name:    name,
+		Size:    size,
+		Mode:    0644,
+		ModTime: file.GetUpdatedAt().AsTime(),
+	})
+	return archive.writer, err
+}
+
+func (archive *tarGzArchive) Close() error {
+	err := archive.writer.Close()
+	if err != nil {
+		return err
+	}
+	return archive.gzip.Close()
+}
+
+// chunkSender sends everything written to it as chunks of at most the capacity of buf
+type chunkSender struct {
+	stream pb.FileService_DownloadArchiveServer
+	buf    []byte
+}
+
+func (sender *chunkSender) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		n := copy(sender.buf[len(sender.buf):cap(sender.buf)], p)
+		sender.buf = sender.buf[:len(sender.buf)+n]
+		written += n
+		p = p[n:]
+
+		if len(sender.buf) == cap(sender.buf) {
+			err := sender.Flush()
+			if err != nil {
+				return written, err
+			}
+		}
+	}
+	return written, nil
+}
+
+// Flush sends the buffered bytes
+func (sender *chunkSender) Flush() error {
+	if len(sender.buf) == 0 {
+		return nil
+	}
+
+	err := sender.stream.Send(&pb.DownloadFileResponse{Chunk: sender.buf})
+	sender.buf = sender.buf[:0]
+	return err
+}
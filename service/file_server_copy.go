@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/Nextasy01/grpc-file-service/pb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// Copies a file the caller may read into a folder of the caller or of another user, the contents aren't duplicated
+func (server *FileServer) Copy(ctx context.Context, req *pb.CopyRequest) (*pb.CopyResponse, error) {
+	if req.GetFileId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "file id is required")
+	}
+
+	original := server.fileStore.Find(req.GetFileId())
+	if original == nil {
+		return nil, status.Errorf(codes.NotFound, "file with id \"%s\" was not found", req.GetFileId())
+	}
+	// the copy gives its owner the contents
+	err := authorizeContents(ctx, original)
+	if err != nil {
+		return nil, err
+	}
+
+	owner := req.GetOwner().GetName()
+	if owner == "" {
+		claims, _ := ClaimsFromContext(ctx)
+		owner = claims.Username
+	}
+	err = authorizeUser(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+
+	copied := proto.Clone(original).(*pb.File)
+	copied.Owner = &pb.Owner{Name: owner}
+	// users the original is shared with don't get access to the copy
+	copied.Grants = nil
+	if req.GetDestination() != "" {
+		copied.Path = req.GetDestination()
+	}
+	if req.GetTitle() != "" {
+		copied.Title = req.GetTitle()
+	}
+
+	// the size is reserved until the copy is accounted to its owner, so concurrent uploads can't exceed the quota
+	err = server.quotas.Reserve(owner, original.GetSize())
+	if err != nil {
+		return nil, status.Errorf(codes.ResourceExhausted,
+			"storage quota of %d bytes is exceeded", server.quotas.Limit(owner))
+	}
+	defer server.quotas.Release(owner, original.GetSize())
+
+	err = server.fileStore.Copy(original.GetId(), copied)
+	switch {
+	case errors.Is(err, ErrInvalidPath):
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, ErrAlreadyExists):
+		return nil, status.Errorf(codes.AlreadyExists, "%s, give the copy another title or destination", err)
+	case errors.Is(err, ErrNotFound):
+		return nil, status.Errorf(codes.NotFound, "file with id \"%s\" was not found", req.GetFileId())
+	case err != nil:
+		log.Println("Cannot copy file ", err)
+		return nil, status.Errorf(codes.Internal, "cannot copy file: %v", err)
+	}
+
+	log.Printf("Copied file %s to %s of %s as file %s", original.GetId(), fullPath(copied), owner, copied.GetId())
+	return &pb.CopyResponse{File: copied}, nil
+}
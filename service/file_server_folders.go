@@ -0,0 +1,154 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/Nextasy01/grpc-file-service/pb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Creates an empty folder of a user
+func (server *FileServer) CreateFolder(ctx context.Context, req *pb.CreateFolderRequest) (*pb.Folder, error) {
+	err := authorizeUser(ctx, req.GetOwner().GetName())
+	if err != nil {
+		return nil, err
+	}
+
+	folder := &pb.Folder{Path: req.GetPath(), Owner: req.GetOwner()}
+	err = server.fileStore.CreateFolder(folder)
+	if err != nil {
+		return nil, folderError(err, req.GetPath())
+	}
+
+	log.Printf("Created folder %s of %s", folder.GetPath(), req.GetOwner().GetName())
+	return folder, nil
+}
+
+// Moves a file to another folder or a folder to another path, only metadata is changed
+func (server *FileServer) Move(ctx context.Context, req *pb.MoveRequest) (*pb.MoveResponse, error) {
+	if req.GetFileId() != "" {
+		_, err := server.findAccessibleFile(ctx, req.GetFileId(), pb.Permission_WRITE)
+		if err != nil {
+			return nil, err
+		}
+
+		file, err := server.fileStore.Move(req.GetFileId(), req.GetDestination(), req.GetTitle())
+		if errors.Is(err, ErrAlreadyExists) {
+			return nil, status.Errorf(codes.AlreadyExists, "%s, give the file another title or destination", err)
+		}
+		if err != nil {
+			return nil, folderError(err, req.GetDestination())
+		}
+
+		log.Printf("Moved file %s to %s", file.GetId(), fullPath(file))
+		return &pb.MoveResponse{File: hideGrants(ctx, file), Moved: 1}, nil
+	}
+
+	owner := req.GetOwner().GetName()
+	err := authorizeUser(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+
+	destination, err := cleanDirectory(req.GetDestination())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	moved, err := server.fileStore.MoveFolder(owner, req.GetFolder(), destination)
+	if errors.Is(err, ErrAlreadyExists) {
+		return nil, folderError(err, destination)
+	}
+	if err != nil {
+		return nil, folderError(err, req.GetFolder())
+	}
+
+	log.Printf("Moved folder %s of %s to %s with %d files", req.GetFolder(), owner, destination, moved)
+	return &pb.MoveResponse{
+		Folder: &pb.Folder{Path: destination, Owner: req.GetOwner()},
+		Moved:  uint32(moved),
+	}, nil
+}
+
+// Deletes a folder, with recursive the files and folders in it as well
+func (server *FileServer) DeleteFolder(ctx context.Context, req *pb.DeleteFolderRequest) (*pb.DeleteFolderResponse, error) {
+	owner := req.GetOwner().GetName()
+	err := authorizeUser(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+
+	dir, err := cleanDirectory(req.GetPath())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if dir == "" {
+		return nil, status.Error(codes.InvalidArgument, "the root folder can't be deleted")
+	}
+
+	found, empty := false, true
+	for _, folder := range server.fileStore.Folders(owner) {
+		if folder.GetPath() == dir {
+			found = true
+		} else if inFolder(folder.GetPath(), dir) {
+			empty = false
+		}
+	}
+	if !found {
+		return nil, status.Errorf(codes.NotFound, "folder \"%s\" was not found", dir)
+	}
+
+	files := make([]*pb.File, 0)
+	for _, file := range server.fileStore.List(owner) {
+		if inFolder(file.GetPath(), dir) {
+			files = append(files, file)
+		}
+	}
+	if !req.GetRecursive() && (!empty || len(files) > 0) {
+		return nil, status.Errorf(codes.FailedPrecondition, "folder \"%s\" is not empty", dir)
+	}
+
+	var deleted uint32
+	for _, file := range files {
+		if req.GetPermanent() {
+			_, err = server.fileStore.Delete(file.GetId())
+		} else {
+			_, err = server.fileStore.Trash(file.GetId())
+		}
+		if errors.Is(err, ErrNotFound) {
+			continue // removed in the meantime
+		}
+		if err != nil {
+			log.Println("Cannot delete file from the store ", err)
+			return nil, status.Errorf(codes.Internal, "cannot delete file: %v", err)
+		}
+		deleted++
+	}
+
+	err = server.fileStore.RemoveFolders(owner, dir)
+	if err != nil {
+		log.Println("Cannot delete folder ", err)
+		return nil, status.Errorf(codes.Internal, "cannot delete folder: %v", err)
+	}
+
+	log.Printf("Deleted folder %s of %s with %d files", dir, owner, deleted)
+	return &pb.DeleteFolderResponse{Files: deleted}, nil
+}
+
+// folderError converts an error of a folder operation of the store to a status
+func folderError(err error, path string) error {
+	switch {
+	case errors.Is(err, ErrInvalidPath):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, ErrAlreadyExists):
+		return status.Errorf(codes.AlreadyExists, "\"%s\" already exists", path)
+	case errors.Is(err, ErrNotFound):
+		return status.Errorf(codes.NotFound, "\"%s\" was not found", path)
+	default:
+		log.Println("Cannot change folders in the store ", err)
+		return status.Errorf(codes.Internal, "cannot change folders: %v", err)
+	}
+}
@@ -0,0 +1,160 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/Nextasy01/grpc-file-service/pb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// lifetime of share links
+const (
+	defaultShareLinkTTL = 24 * time.Hour
+	maxShareLinkTTL     = 30 * 24 * time.Hour
+)
+
+// EnableShareLinks lets owners create links to their files which can be downloaded
+// without an account, the tokens of the links are signed by jwtManager
+func (server *FileServer) EnableShareLinks(links *ShareLinkStore, jwtManager *JWTManager) {
+	server.shareLinks = links
+	server.linkSigner = jwtManager
+}
+
+// Creates a link anyone with its token can download a file of the caller with
+func (server *FileServer) CreateShareLink(ctx context.Context, req *pb.CreateShareLinkRequest) (*pb.ShareLink, error) {
+	err := server.checkShareLinks()
+	if err != nil {
+		return nil, err
+	}
+
+	ttl := defaultShareLinkTTL
+	if req.GetExpiresIn() != nil {
+		ttl = req.GetExpiresIn().AsDuration()
+		if req.GetExpiresIn().CheckValid() != nil || ttl <= 0 || ttl > maxShareLinkTTL {
+			return nil, status.Errorf(codes.InvalidArgument, "share links have to expire within %s", maxShareLinkTTL)
+		}
+	}
+
+	file, err := server.findOwnedFile(ctx, req.GetFileId())
+	if err != nil {
+		return nil, err
+	}
+	err = authorizeSharing(ctx, file)
+	if err != nil {
+		return nil, err
+	}
+
+	link, err := server.shareLinks.Create(file, ttl, req.GetMaxDownloads(), req.GetPassword())
+	if err != nil {
+		log.Println("Cannot create share link ", err)
+		return nil, status.Errorf(codes.Internal, "cannot create share link: %v", err)
+	}
+	token, err := server.linkSigner.GenerateLink(link)
+	if err != nil {
+		server.shareLinks.Revoke(link.ID)
+		log.Println("Cannot sign share link ", err)
+		return nil, status.Errorf(codes.Internal, "cannot sign share link: %v", err)
+	}
+
+	log.Printf("Created share link %s to file %s expiring at %s", link.ID, file.GetId(), link.ExpiresAt.UTC())
+	res := link.Proto()
+	res.Token = token
+	return res, nil
+}
+
+// Returns the share links of the files of a user which haven't expired
+func (server *FileServer) ListShareLinks(ctx context.Context, req *pb.ListShareLinksRequest) (*pb.ListShareLinksResponse, error) {
+	err := server.checkShareLinks()
+	if err != nil {
+		return nil, err
+	}
+
+	err = authorizeUser(ctx, req.GetOwner().GetName())
+	if err != nil {
+		return nil, err
+	}
+
+	links := server.shareLinks.List(req.GetOwner().GetName(), req.GetFileId())
+	res := &pb.ListShareLinksResponse{Links: make([]*pb.ShareLink, 0, len(links))}
+	for _, link := range links {
+		res.Links = append(res.Links, link.Proto())
+	}
+	return res, nil
+}
+
+// Revokes a share link, its token can't be used to download the file anymore
+func (server *FileServer) RevokeShareLink(ctx context.Context, req *pb.RevokeShareLinkRequest) (*pb.RevokeShareLinkResponse, error) {
+	err := server.checkShareLinks()
+	if err != nil {
+		return nil, err
+	}
+
+	link := server.shareLinks.Find(req.GetLinkId())
+	if link == nil {
+		return nil, status.Errorf(codes.NotFound, "share link \"%s\" was not found", req.GetLinkId())
+	}
+	err = authorizeUser(ctx, link.Owner)
+	if err != nil {
+		return nil, err
+	}
+
+	link, err = server.shareLinks.Revoke(link.ID)
+	if errors.Is(err, ErrNotFound) {
+		return nil, status.Errorf(codes.NotFound, "share link \"%s\" was not found", req.GetLinkId())
+	}
+
+	log.Printf("Revoked share link %s to file %s", link.ID, link.FileID)
+	return &pb.RevokeShareLinkResponse{Link: link.Proto()}, nil
+}
+
+// Downloads the file of a share link, the token of the link is the only authorization
+func (server *FileServer) DownloadShared(req *pb.DownloadSharedRequest, stream pb.FileService_DownloadSharedServer) error {
+	err := server.checkShareLinks()
+	if err != nil {
+		return err
+	}
+
+	claims, err := server.linkSigner.VerifyLink(req.GetToken())
+	if err != nil {
+		return status.Errorf(codes.Unauthenticated, "share link is invalid: %v", err)
+	}
+
+	file := server.fileStore.Find(claims.FileID)
+	if file == nil {
+		return status.Error(codes.NotFound, "the shared file was deleted")
+	}
+
+	link, err := server.shareLinks.Use(claims.ID, req.GetPassword())
+	switch {
+	case errors.Is(err, ErrNotFound) && server.shareLinks.Lost(claims.Store):
+		return status.Error(codes.NotFound, "share link was lost when the server restarted, ask the owner of the file for a new link")
+	case errors.Is(err, ErrNotFound):
+		return status.Error(codes.NotFound, "share link was revoked or has expired")
+	case errors.Is(err, ErrWrongPassword):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, ErrLinkExhausted):
+		return status.Error(codes.ResourceExhausted, err.Error())
+	case err != nil:
+		return status.Errorf(codes.Internal, "cannot use share link: %v", err)
+	}
+
+	log.Printf("Sending file %s through share link %s, download %d", file.GetId(), link.ID, link.Downloads)
+	return server.sendFile(&pb.DownloadFileRequest{
+		FileId:    file.GetId(),
+		Offset:    req.GetOffset(),
+		Length:    req.GetLength(),
+		ChunkSize: req.GetChunkSize(),
+	}, file, stream)
+}
+
+// checkShareLinks makes sure share links are enabled on the server
+func (server *FileServer) checkShareLinks() error {
+	if server.shareLinks == nil {
+		return status.Error(codes.FailedPrecondition, "share links are not enabled on this server")
+	}
+	return nil
+}
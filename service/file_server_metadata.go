@@ -0,0 +1,188 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+
+	"github.com/Nextasy01/grpc-file-service/pb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrInvalidMetadata is returned for labels or tags which can't be stored
+var ErrInvalidMetadata = errors.New("invalid metadata")
+
+// limits of the user defined metadata of a file
+const (
+	maxLabels        = 64
+	maxLabelValueLen = 256
+	maxTags          = 64
+	maxTagLen        = 64
+)
+
+// fields of a file which can be updated through UpdateMetadata
+const (
+	labelsField = "labels"
+	tagsField   = "tags"
+)
+
+// label keys are sent as header names when the file is downloaded
+var labelKeyPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]{0,62}$`)
+
+// largest number of files BatchGetFiles returns at once
+const maxBatchSize = 1000
+
+// Returns the metadata of a file the caller may read
+func (server *FileServer) GetFile(ctx context.Context, req *pb.GetFileRequest) (*pb.File, error) {
+	file, err := server.findAccessibleFile(ctx, req.GetFileId(), pb.Permission_READ)
+	if err != nil {
+		return nil, err
+	}
+	return hideGrants(ctx, file), nil
+}
+
+// Returns the metadata of several files, it fails if any of them can't be read by the caller
+func (server *FileServer) BatchGetFiles(ctx context.Context, req *pb.BatchGetFilesRequest) (*pb.BatchGetFilesResponse, error) {
+	if len(req.GetFileIds()) > maxBatchSize {
+		return nil, status.Errorf(codes.InvalidArgument, "at most %d files can be requested at once", maxBatchSize)
+	}
+
+	res := &pb.BatchGetFilesResponse{Files: make([]*pb.File, 0, len(req.GetFileIds()))}
+	for _, id := range req.GetFileIds() {
+		file, err := server.findAccessibleFile(ctx, id, pb.Permission_READ)
+		if status.Code(err) == codes.PermissionDenied {
+			return nil, status.Errorf(codes.PermissionDenied, "file \"%s\": %s", id, status.Convert(err).Message())
+		}
+		if err != nil {
+			return nil, err
+		}
+		res.Files = append(res.Files, hideGrants(ctx, file))
+	}
+	return res, nil
+}
+
+// Changes the labels and tags of a file, only the fields in the update mask are changed
+func (server *FileServer) UpdateMetadata(ctx context.Context, req *pb.UpdateMetadataRequest) (*pb.UpdateMetadataResponse, error) {
+	paths := req.GetUpdateMask().GetPaths()
+	if len(paths) == 0 {
+		paths = []string{labelsField, tagsField}
+	}
+	for _, path := range paths {
+		err := checkMaskPath(path)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+
+	file, err := server.findAccessibleFile(ctx, req.GetFileId(), pb.Permission_WRITE)
+	if err != nil {
+		return nil, err
+	}
+
+	updated, err := server.fileStore.UpdateMetadata(file.GetId(), func(file *pb.File) error {
+		applyMetadata(file, req.GetFile(), paths)
+		return checkMetadata(file)
+	})
+	switch {
+	case errors.Is(err, ErrInvalidMetadata):
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, ErrNotFound):
+		return nil, status.Errorf(codes.NotFound, "file with id \"%s\" was not found", req.GetFileId())
+	case err != nil:
+		log.Println("Cannot update metadata ", err)
+		return nil, status.Errorf(codes.Internal, "cannot update metadata: %v", err)
+	}
+
+	log.Printf("Updated metadata of file %s", updated.GetId())
+	return &pb.UpdateMetadataResponse{File: hideGrants(ctx, updated)}, nil
+}
+
+// checkMaskPath makes sure a field of the update mask can be updated
+func checkMaskPath(path string) error {
+	if path == labelsField || path == tagsField {
+		return nil
+	}
+
+	if !strings.HasPrefix(path, labelsField+".") {
+		return fmt.Errorf("%w: field \"%s\" can't be updated", ErrInvalidMetadata, path)
+	}
+	key := strings.TrimPrefix(path, labelsField+".")
+	if !labelKeyPattern.MatchString(key) {
+		return fmt.Errorf("%w: invalid label key \"%s\"", ErrInvalidMetadata, key)
+	}
+	return nil
+}
+
+// applyMetadata copies the fields in paths from update to file
+func applyMetadata(file, update *pb.File, paths []string) {
+	for _, path := range paths {
+		switch path {
+		case labelsField:
+			file.Labels = update.GetLabels()
+		case tagsField:
+			file.Tags = update.GetTags()
+		default:
+			key := strings.TrimPrefix(path, labelsField+".")
+			value, ok := update.GetLabels()[key]
+			if !ok {
+				delete(file.Labels, key)
+				continue
+			}
+			if file.Labels == nil {
+				file.Labels = make(map[string]string)
+			}
+			file.Labels[key] = value
+		}
+	}
+}
+
+// checkMetadata validates the labels and tags of a file and removes duplicate tags
+func checkMetadata(file *pb.File) error {
+	if len(file.GetLabels()) > maxLabels {
+		return fmt.Errorf("%w: a file can have at most %d labels", ErrInvalidMetadata, maxLabels)
+	}
+	for key, value := range file.GetLabels() {
+		if !labelKeyPattern.MatchString(key) {
+			return fmt.Errorf("%w: label key \"%s\" has to be lower case letters, digits, '.', '_' or '-' and at most 63 characters long",
+				ErrInvalidMetadata, key)
+		}
+		if len(value) > maxLabelValueLen || !printable(value) {
+			return fmt.Errorf("%w: value of label \"%s\" has to be printable ASCII and at most %d characters long",
+				ErrInvalidMetadata, key, maxLabelValueLen)
+		}
+	}
+
+	tags := make([]string, 0, len(file.GetTags()))
+	seen := make(map[string]bool)
+	for _, tag := range file.GetTags() {
+		if tag == "" || len(tag) > maxTagLen || !printable(tag) || strings.Contains(tag, ",") {
+			return fmt.Errorf("%w: tag \"%s\" has to be printable ASCII without commas and 1 to %d characters long",
+				ErrInvalidMetadata, tag, maxTagLen)
+		}
+		if !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+	if len(tags) > maxTags {
+		return fmt.Errorf("%w: a file can have at most %d tags", ErrInvalidMetadata, maxTags)
+	}
+	if len(tags) > 0 {
+		file.Tags = tags
+	}
+	return nil
+}
+
+// printable reports whether s only has printable ASCII characters, which are allowed in header values
+func printable(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < 0x20 || s[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
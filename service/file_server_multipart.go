@@ -0,0 +1,308 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/Nextasy01/grpc-file-service/pb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// highest part number of a multipart upload
+const maxPartNumber = 10000
+
+// Starts an upload whose parts can be sent concurrently with UploadPart
+func (server *FileServer) InitiateMultipartUpload(ctx context.Context, req *pb.InitiateMultipartUploadRequest) (*pb.MultipartUpload, error) {
+	file := req.GetFile()
+	if file == nil {
+		return nil, status.Error(codes.InvalidArgument, "file is required")
+	}
+
+	if file.GetSize() > maxFileSize {
+		return nil, status.Errorf(codes.InvalidArgument,
+			"the file size is too large. Expected < %d bytes", maxFileSize)
+	}
+
+	_, err := cleanDirectory(file.GetPath())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	err = checkMetadata(file)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	err = server.quotas.Check(file.GetOwner().GetName(), file.GetSize())
+	if err != nil {
+		return nil, status.Errorf(codes.ResourceExhausted,
+			"storage quota of %d bytes is exceeded", server.quotas.Limit(file.GetOwner().GetName()))
+	}
+
+	upload, err := server.multipartUploads.Create(file)
+	if err != nil {
+		log.Println("Cannot create multipart upload ", err)
+		return nil, status.Errorf(codes.Internal, "cannot create multipart upload: %v", err)
+	}
+
+	log.Printf("Created multipart upload %s for file - %s", upload.ID, file.GetTitle())
+	return upload.Proto(), nil
+}
+
+// Receives a single part of a multipart upload, sending a part again replaces it
+func (server *FileServer) UploadPart(stream pb.FileService_UploadPartServer) error {
+	err := server.advertiseChunkSizes(stream)
+	if err != nil {
+		return err
+	}
+
+	req, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+
+	number := req.GetPartNumber()
+	if number < 1 || number > maxPartNumber {
+		return status.Errorf(codes.InvalidArgument, "part number must be between 1 and %d", maxPartNumber)
+	}
+
+	upload, err := server.multipartUploads.StartPart(req.GetUploadId())
+	if errors.Is(err, ErrNotFound) {
+		return status.Errorf(codes.NotFound, "multipart upload \"%s\" was not found", req.GetUploadId())
+	}
+	if err != nil {
+		return status.Errorf(codes.Aborted, "cannot upload part: %v", err)
+	}
+	finished := false
+	finish := func() {
+		if !finished {
+			finished = true
+			server.multipartUploads.FinishPart(upload)
+		}
+	}
+	defer finish()
+
+	err = authorizeOwner(stream.Context(), upload.File)
+	if err != nil {
+		return err
+	}
+
+	temp, err := os.CreateTemp(upload.dir, ".part-*")
+	if err != nil {
+		log.Println("Cannot create part file ", err)
+		return status.Errorf(codes.Internal, "cannot store part: %v", err)
+	}
+	defer os.Remove(temp.Name()) // fails once the part is saved
+	defer temp.Close()
+
+	// the part counts against the quota while it's received, and once it's saved until the upload is completed or aborted
+	owner := upload.File.GetOwner().GetName()
+	var size uint64
+	var reserved uint64
+	defer func() { server.quotas.Release(owner, reserved) }()
+
+	hash := sha256.New()
+	writer := io.MultiWriter(temp, hash)
+	chunk := req.GetChunk()
+	for {
+		if size+uint64(len(chunk)) > maxFileSize {
+			return status.Errorf(codes.InvalidArgument,
+				"the part size is too large. Expected < %d bytes", maxFileSize)
+		}
+
+		err = server.checkChunk(chunk)
+		if err != nil {
+			return err
+		}
+
+		err = server.quotas.Reserve(owner, uint64(len(chunk)))
+		if err != nil {
+			return status.Errorf(codes.ResourceExhausted,
+				"storage quota of %d bytes is exceeded", server.quotas.Limit(owner))
+		}
+		size += uint64(len(chunk))
+		reserved += uint64(len(chunk))
+
+		_, err = writer.Write(chunk)
+		if err != nil {
+			log.Println("Cannot write a chunk of data", err)
+			return status.Errorf(codes.Internal, "cannot write chunk data: %v", err)
+		}
+
+		err := contextError(stream.Context())
+		if err != nil {
+			return err
+		}
+
+		req, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			log.Println("Cannot receive a chunk of data", err)
+			return err
+		}
+		chunk = req.GetChunk()
+	}
+
+	err = temp.Sync()
+	if err == nil {
+		err = temp.Close()
+	}
+	part := UploadedPart{Number: number, Size: size, Checksum: hex.EncodeToString(hash.Sum(nil))}
+	if err == nil {
+		err = server.multipartUploads.SavePart(upload, part, temp.Name())
+	}
+	if err != nil {
+		log.Println("Cannot save part ", err)
+		return status.Errorf(codes.Internal, "cannot store part: %v", err)
+	}
+	reserved = 0 // kept by the upload
+	// finished before responding, so the client can complete or abort the upload right away
+	finish()
+
+	log.Printf("Received part %d of multipart upload %s with size %d bytes", number, upload.ID, size)
+	return stream.SendAndClose(&pb.UploadPartResponse{PartNumber: number, Size: size, Checksum: part.Checksum})
+}
+
+// Assembles the listed parts into the file and saves it
+func (server *FileServer) CompleteMultipartUpload(ctx context.Context, req *pb.CompleteMultipartUploadRequest) (*pb.CompleteMultipartUploadResponse, error) {
+	upload, err := server.multipartUploads.Acquire(req.GetUploadId())
+	if errors.Is(err, ErrNotFound) {
+		return nil, status.Errorf(codes.NotFound, "multipart upload \"%s\" was not found", req.GetUploadId())
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Aborted, "cannot complete upload: %v", err)
+	}
+
+	file, err := server.assembleParts(ctx, upload, req.GetParts())
+	if err != nil {
+		server.multipartUploads.Release(upload)
+		return nil, err
+	}
+
+	err = server.multipartUploads.Complete(upload)
+	if err != nil {
+		log.Printf("Cannot remove parts of multipart upload %s: %v", upload.ID, err)
+	}
+
+	log.Printf("Saved file - %s with size %d bytes from %d parts", file.GetTitle(), file.GetSize(), len(req.GetParts()))
+	return &pb.CompleteMultipartUploadResponse{File: file}, nil
+}
+
+// assembleParts writes the parts of an acquired upload to the store in the order of the manifest
+func (server *FileServer) assembleParts(ctx context.Context, upload *MultipartUpload, manifest []*pb.CompletedPart) (*pb.File, error) {
+	err := authorizeOwner(ctx, upload.File)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(manifest) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "at least one part is required")
+	}
+
+	var size uint64
+	for i, completed := range manifest {
+		if i > 0 && completed.GetPartNumber() <= manifest[i-1].GetPartNumber() {
+			return nil, status.Error(codes.InvalidArgument, "parts must be listed in ascending order")
+		}
+
+		part, ok := upload.Part(completed.GetPartNumber())
+		if !ok {
+			return nil, status.Errorf(codes.FailedPrecondition, "part %d was not uploaded", completed.GetPartNumber())
+		}
+		if completed.GetChecksum() != "" && !strings.EqualFold(completed.GetChecksum(), part.Checksum) {
+			return nil, status.Errorf(codes.DataLoss,
+				"checksum mismatch of part %d: expected %s, received data has %s", part.Number, completed.GetChecksum(), part.Checksum)
+		}
+		size += part.Size
+	}
+
+	if upload.File.GetSize() != 0 && size != upload.File.GetSize() {
+		return nil, status.Errorf(codes.InvalidArgument,
+			"parts have %d bytes, expected %d", size, upload.File.GetSize())
+	}
+	if size > maxFileSize {
+		return nil, status.Errorf(codes.InvalidArgument,
+			"the file size is too large. Expected < %d bytes", maxFileSize)
+	}
+
+	// the quota needs no check, the parts are reserved until the upload is removed after the file is saved
+
+	// the upload keeps its file untouched so completing can be retried
+	file := proto.Clone(upload.File).(*pb.File)
+	writer, err := server.createWriter(file)
+	if err != nil {
+		return nil, err
+	}
+	defer writer.Abort() // no-op once the file is committed
+
+	for _, completed := range manifest {
+		err := contextError(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		err = copyPart(writer, upload.partPath(completed.GetPartNumber()))
+		if err != nil {
+			log.Println("Cannot assemble parts ", err)
+			return nil, status.Errorf(codes.Internal, "cannot assemble parts: %v", err)
+		}
+	}
+
+	checksum := upload.File.GetChecksum()
+	if checksum != "" && !strings.EqualFold(checksum, writer.Checksum()) {
+		return nil, status.Errorf(codes.DataLoss,
+			"checksum mismatch: expected %s, received data has %s", checksum, writer.Checksum())
+	}
+
+	err = writer.Commit()
+	if err != nil {
+		log.Println("Cannot save file to the store ", err)
+		return nil, status.Errorf(codes.Internal, "cannot save file: %v", err)
+	}
+	return file, nil
+}
+
+func copyPart(writer io.Writer, path string) error {
+	part, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer part.Close()
+
+	_, err = io.Copy(writer, part)
+	return err
+}
+
+// Discards a multipart upload and its parts
+func (server *FileServer) AbortMultipartUpload(ctx context.Context, req *pb.AbortMultipartUploadRequest) (*pb.AbortMultipartUploadResponse, error) {
+	upload := server.multipartUploads.Find(req.GetUploadId())
+	if upload == nil {
+		return nil, status.Errorf(codes.NotFound, "multipart upload \"%s\" was not found", req.GetUploadId())
+	}
+
+	err := authorizeOwner(ctx, upload.File)
+	if err != nil {
+		return nil, err
+	}
+
+	err = server.multipartUploads.Remove(upload.ID)
+	if errors.Is(err, ErrSessionBusy) {
+		return nil, status.Errorf(codes.Aborted, "cannot abort upload: %v", err)
+	}
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		log.Println("Cannot remove multipart upload ", err)
+		return nil, status.Errorf(codes.Internal, "cannot remove parts: %v", err)
+	}
+
+	log.Printf("Aborted multipart upload %s", upload.ID)
+	return &pb.AbortMultipartUploadResponse{}, nil
+}
@@ -0,0 +1,94 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"path"
+
+	"github.com/Nextasy01/grpc-file-service/pb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Streams the files matching a query
+func (server *FileServer) Search(req *pb.SearchRequest, stream pb.FileService_SearchServer) error {
+	// without an owner only admins pass
+	err := authorizeUser(stream.Context(), req.GetOwner().GetName())
+	if err != nil {
+		return err
+	}
+
+	query, err := searchQuery(req)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	files := server.fileStore.Search(query)
+	for _, file := range files {
+		err := contextError(stream.Context())
+		if err != nil {
+			return err
+		}
+
+		err = stream.Send(&pb.ListFilesResponse{File: file})
+		if err != nil {
+			return status.Errorf(codes.Internal, "failed to send response: %v", err)
+		}
+	}
+
+	log.Printf("Search returned %d files", len(files))
+	return nil
+}
+
+// searchQuery validates a search request and converts it to a query of the store
+func searchQuery(req *pb.SearchRequest) (FileQuery, error) {
+	if _, err := path.Match(req.GetName(), ""); err != nil {
+		return FileQuery{}, fmt.Errorf("invalid name pattern \"%s\": %w", req.GetName(), err)
+	}
+	if req.GetMaxSize() > 0 && req.GetMinSize() > req.GetMaxSize() {
+		return FileQuery{}, fmt.Errorf("min_size %d is larger than max_size %d", req.GetMinSize(), req.GetMaxSize())
+	}
+
+	created, err := timeSpan(req.GetCreated())
+	if err != nil {
+		return FileQuery{}, fmt.Errorf("invalid created range: %w", err)
+	}
+	updated, err := timeSpan(req.GetUpdated())
+	if err != nil {
+		return FileQuery{}, fmt.Errorf("invalid updated range: %w", err)
+	}
+
+	return FileQuery{
+		Owner:       req.GetOwner().GetName(),
+		Name:        req.GetName(),
+		MinSize:     req.GetMinSize(),
+		MaxSize:     req.GetMaxSize(),
+		Created:     created,
+		Updated:     updated,
+		ContentType: req.GetContentType(),
+		Labels:      req.GetLabels(),
+	}, nil
+}
+
+func timeSpan(timeRange *pb.TimeRange) (TimeSpan, error) {
+	var span TimeSpan
+	if timeRange.GetStart() != nil {
+		err := timeRange.GetStart().CheckValid()
+		if err != nil {
+			return span, err
+		}
+		span.Start = timeRange.GetStart().AsTime()
+	}
+	if timeRange.GetEnd() != nil {
+		err := timeRange.GetEnd().CheckValid()
+		if err != nil {
+			return span, err
+		}
+		span.End = timeRange.GetEnd().AsTime()
+	}
+
+	if !span.Start.IsZero() && !span.End.IsZero() && !span.Start.Before(span.End) {
+		return span, fmt.Errorf("start %s is not before end %s", span.Start, span.End)
+	}
+	return span, nil
+}
@@ -0,0 +1,234 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"strings"
+
+	"github.com/Nextasy01/grpc-file-service/pb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// the received data is synced to disk at least this often during resumable uploads
+const sessionSyncSize = 4 << 20
+
+// Starts an upload which can be continued with ResumeUpload after a failure
+func (server *FileServer) CreateUploadSession(ctx context.Context, req *pb.CreateUploadSessionRequest) (*pb.UploadSession, error) {
+	file := req.GetFile()
+	if file == nil {
+		return nil, status.Error(codes.InvalidArgument, "file is required")
+	}
+
+	if file.GetSize() > maxFileSize {
+		return nil, status.Errorf(codes.InvalidArgument,
+			"the file size is too large. Expected < %d bytes", maxFileSize)
+	}
+
+	err := server.quotas.Check(file.GetOwner().GetName(), file.GetSize())
+	if err != nil {
+		return nil, status.Errorf(codes.ResourceExhausted,
+			"storage quota of %d bytes is exceeded", server.quotas.Limit(file.GetOwner().GetName()))
+	}
+
+	writer, err := server.createWriter(file)
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := server.uploadSessions.Create(file, writer)
+	if err != nil {
+		writer.Abort()
+		log.Println("Cannot save upload session ", err)
+		return nil, status.Errorf(codes.Internal, "cannot create upload session: %v", err)
+	}
+	log.Printf("Created upload session %s for file - %s", session.ID, file.GetTitle())
+
+	return session.Proto(), nil
+}
+
+// Returns the state of an upload session, including the offset to resume from
+func (server *FileServer) GetUploadSession(ctx context.Context, req *pb.GetUploadSessionRequest) (*pb.UploadSession, error) {
+	session := server.uploadSessions.Find(req.GetSessionId())
+	if session == nil {
+		return nil, status.Errorf(codes.NotFound, "upload session \"%s\" was not found", req.GetSessionId())
+	}
+
+	err := authorizeOwner(ctx, session.File)
+	if err != nil {
+		return nil, err
+	}
+
+	return session.Proto(), nil
+}
+
+// Continues an upload session from the offset stored on the server
+func (server *FileServer) ResumeUpload(stream pb.FileService_ResumeUploadServer) error {
+	err := server.advertiseChunkSizes(stream)
+	if err != nil {
+		return err
+	}
+
+	req, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+
+	session, err := server.uploadSessions.Acquire(req.GetSessionId())
+	if errors.Is(err, ErrNotFound) {
+		return status.Errorf(codes.NotFound, "upload session \"%s\" was not found", req.GetSessionId())
+	}
+	if err != nil {
+		return status.Errorf(codes.Aborted, "cannot resume upload: %v", err)
+	}
+	defer server.uploadSessions.Release(session)
+
+	err = authorizeOwner(stream.Context(), session.File)
+	if err != nil {
+		return err
+	}
+
+	if req.GetOffset() != session.Offset {
+		return status.Errorf(codes.FailedPrecondition,
+			"upload has to be resumed from offset %d", session.Offset)
+	}
+
+	// data received after the offset wasn't stored durably, e.g. when a sync failed, and is written again
+	err = session.writer.Truncate(session.Offset)
+	if err != nil {
+		log.Println("Cannot discard data after the offset ", err)
+		server.abortSession(session)
+		return status.Errorf(codes.Internal, "cannot resume upload: %v", err)
+	}
+
+	log.Printf("Resuming upload session %s from offset %d", session.ID, session.Offset)
+
+	// the data received by the session counts against the quota until the file is saved
+	owner := session.File.GetOwner().GetName()
+	written := session.Offset
+	reserved := uint64(0)
+	defer func() { server.quotas.Release(owner, reserved) }()
+	reserve := func(size uint64) error {
+		err := server.quotas.Reserve(owner, size)
+		if err != nil {
+			return status.Errorf(codes.ResourceExhausted,
+				"storage quota of %d bytes is exceeded", server.quotas.Limit(owner))
+		}
+		reserved += size
+		return nil
+	}
+
+	err = reserve(written)
+	if err != nil {
+		return err
+	}
+	saveProgress := func() error {
+		if written == session.Offset {
+			return nil
+		}
+		err := session.writer.Sync()
+		if err != nil {
+			return err
+		}
+		return server.uploadSessions.SetOffset(session, written)
+	}
+	// keepProgress saves what was received before the stream fails, the upload is resumed from the saved offset
+	keepProgress := func() {
+		err := saveProgress()
+		if err != nil {
+			log.Printf("Cannot save progress of upload session %s: %v", session.ID, err)
+		}
+	}
+
+	chunk := req.GetChunk()
+	for {
+		if written+uint64(len(chunk)) > session.File.GetSize() {
+			keepProgress()
+			return status.Errorf(codes.InvalidArgument,
+				"received more data than the declared size of %d bytes", session.File.GetSize())
+		}
+
+		err = server.checkChunk(chunk)
+		if err == nil {
+			err = reserve(uint64(len(chunk)))
+		}
+		if err != nil {
+			keepProgress()
+			return err
+		}
+
+		_, err = session.writer.Write(chunk)
+		if err != nil {
+			// the written data can't be trusted anymore
+			log.Println("Cannot write a chunk of data", err)
+			server.abortSession(session)
+			return status.Errorf(codes.Internal, "cannot write chunk data: %v", err)
+		}
+		written += uint64(len(chunk))
+
+		if written-session.Offset >= sessionSyncSize {
+			err = saveProgress()
+			if err != nil {
+				server.abortSession(session)
+				return status.Errorf(codes.Internal, "cannot store chunk data: %v", err)
+			}
+		}
+
+		err := contextError(stream.Context())
+		if err != nil {
+			keepProgress()
+			return err
+		}
+
+		req, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			log.Println("Cannot receive a chunk of data", err)
+			keepProgress()
+			return err
+		}
+		chunk = req.GetChunk()
+	}
+
+	err = saveProgress()
+	if err != nil {
+		server.abortSession(session)
+		return status.Errorf(codes.Internal, "cannot store chunk data: %v", err)
+	}
+
+	res := &pb.ResumeUploadResponse{Session: session.Proto()}
+
+	if session.Offset == session.File.GetSize() {
+		checksum := session.File.GetChecksum()
+		if checksum != "" && !strings.EqualFold(checksum, session.writer.Checksum()) {
+			server.abortSession(session)
+			return status.Errorf(codes.DataLoss,
+				"checksum mismatch: expected %s, received data has %s", checksum, session.writer.Checksum())
+		}
+
+		err = session.writer.Commit()
+		server.uploadSessions.Remove(session.ID)
+		if err != nil {
+			log.Println("Cannot save file to the store ", err)
+			return status.Errorf(codes.Internal, "cannot save file: %v", err)
+		}
+
+		log.Printf("Saved file - %s with size %d bytes", session.File.GetTitle(), session.File.GetSize())
+		res.File = session.File
+	}
+
+	return stream.SendAndClose(res)
+}
+
+// abortSession removes a session whose data can't be used anymore
+func (server *FileServer) abortSession(session *UploadSession) {
+	server.uploadSessions.Remove(session.ID)
+	err := session.writer.Abort()
+	if err != nil {
+		log.Printf("Cannot remove data of upload session %s: %v", session.ID, err)
+	}
+}
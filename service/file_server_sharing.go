@@ -0,0 +1,211 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/Nextasy01/grpc-file-service/pb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// ErrNotShared is returned when access is revoked from a user the file isn't shared with
+var ErrNotShared = errors.New("file is not shared with the user")
+
+// largest number of users a file can be shared with
+const maxGrants = 100
+
+// Shares a file of the caller with another user, sharing it again changes the permission
+func (server *FileServer) ShareFile(ctx context.Context, req *pb.ShareFileRequest) (*pb.ShareFileResponse, error) {
+	username := req.GetUser().GetName()
+	if username == "" {
+		return nil, status.Error(codes.InvalidArgument, "user to share the file with is required")
+	}
+	if req.GetPermission() == pb.Permission_PERMISSION_UNSPECIFIED {
+		return nil, status.Error(codes.InvalidArgument, "permission to share the file with is required")
+	}
+	if _, ok := pb.Permission_name[int32(req.GetPermission())]; !ok {
+		return nil, status.Errorf(codes.InvalidArgument, "unknown permission %d", req.GetPermission())
+	}
+
+	file, err := server.findOwnedFile(ctx, req.GetFileId())
+	if err != nil {
+		return nil, err
+	}
+	err = authorizeSharing(ctx, file)
+	if err != nil {
+		return nil, err
+	}
+	if file.GetOwner().GetName() == username {
+		return nil, status.Errorf(codes.InvalidArgument, "the file already belongs to %s", username)
+	}
+
+	updated, err := server.updateGrants(file.GetId(), func(file *pb.File) error {
+		grant := findGrant(file, username)
+		if grant == nil {
+			if len(file.GetGrants()) >= maxGrants {
+				return fmt.Errorf("%w: a file can be shared with at most %d users", ErrInvalidMetadata, maxGrants)
+			}
+			grant = &pb.Grant{User: &pb.Owner{Name: username}}
+			file.Grants = append(file.Grants, grant)
+		}
+		grant.Permission = req.GetPermission()
+		grant.GrantedAt = timestamppb.Now()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("Shared file %s with %s for %s", updated.GetId(), username, req.GetPermission())
+	return &pb.ShareFileResponse{File: updated}, nil
+}
+
+// Revokes the access of a user to a file of the caller
+func (server *FileServer) UnshareFile(ctx context.Context, req *pb.UnshareFileRequest) (*pb.UnshareFileResponse, error) {
+	username := req.GetUser().GetName()
+	if username == "" {
+		return nil, status.Error(codes.InvalidArgument, "user to revoke the access of is required")
+	}
+
+	file, err := server.findOwnedFile(ctx, req.GetFileId())
+	if err != nil {
+		return nil, err
+	}
+
+	updated, err := server.updateGrants(file.GetId(), func(file *pb.File) error {
+		grants := make([]*pb.Grant, 0, len(file.GetGrants()))
+		for _, grant := range file.GetGrants() {
+			if grant.GetUser().GetName() != username {
+				grants = append(grants, grant)
+			}
+		}
+		if len(grants) == len(file.GetGrants()) {
+			return fmt.Errorf("%w %s", ErrNotShared, username)
+		}
+		file.Grants = grants
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("Stopped sharing file %s with %s", updated.GetId(), username)
+	return &pb.UnshareFileResponse{File: updated}, nil
+}
+
+// updateGrants changes the users the file is shared with
+func (server *FileServer) updateGrants(id string, update func(file *pb.File) error) (*pb.File, error) {
+	updated, err := server.fileStore.UpdateMetadata(id, update)
+	switch {
+	case errors.Is(err, ErrInvalidMetadata):
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, ErrNotShared):
+		return nil, status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, ErrNotFound):
+		return nil, status.Errorf(codes.NotFound, "file with id \"%s\" was not found", id)
+	case err != nil:
+		log.Println("Cannot change the sharing of a file ", err)
+		return nil, status.Errorf(codes.Internal, "cannot change the sharing of the file: %v", err)
+	}
+	return updated, nil
+}
+
+// findAccessibleFile returns the file if it exists and the caller may access it with the permission
+func (server *FileServer) findAccessibleFile(ctx context.Context, id string, permission pb.Permission) (*pb.File, error) {
+	if id == "" {
+		return nil, status.Error(codes.InvalidArgument, "file id is required")
+	}
+
+	file := server.fileStore.Find(id)
+	if file == nil {
+		return nil, status.Errorf(codes.NotFound, "file with id \"%s\" was not found", id)
+	}
+
+	err := authorizeAccess(ctx, file, permission)
+	if err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+// hideGrants returns the file without the users it is shared with unless the caller owns it,
+// the users a file is shared with don't learn about each other
+func hideGrants(ctx context.Context, file *pb.File) *pb.File {
+	claims, ok := ClaimsFromContext(ctx)
+	if len(file.GetGrants()) == 0 || ok && claims.Username == file.GetOwner().GetName() {
+		return file
+	}
+
+	hidden := proto.Clone(file).(*pb.File)
+	hidden.Grants = nil
+	return hidden
+}
+
+// authorizeAccess checks that the caller owns the file, was granted the permission or is an admin
+func authorizeAccess(ctx context.Context, file *pb.File, permission pb.Permission) error {
+	claims, ok := ClaimsFromContext(ctx)
+	if !ok {
+		return status.Error(codes.Unauthenticated, "caller is not authenticated")
+	}
+
+	if claims.Role == adminRole || canAccess(claims.Username, file, permission) {
+		return nil
+	}
+
+	return status.Errorf(codes.PermissionDenied, "the file is not shared with you for %s", permission)
+}
+
+// authorizeContents checks that the caller may read the contents of the file. Admins manage
+// every file, but only the owner and the users the file is shared with get its contents
+func authorizeContents(ctx context.Context, file *pb.File) error {
+	claims, ok := ClaimsFromContext(ctx)
+	if !ok {
+		return status.Error(codes.Unauthenticated, "caller is not authenticated")
+	}
+
+	if canAccess(claims.Username, file, pb.Permission_READ) {
+		return nil
+	}
+
+	return status.Error(codes.PermissionDenied, "the file is not shared with you")
+}
+
+// authorizeSharing checks that the caller owns the file, admins can't share the files of other users
+// since that would give them the contents
+func authorizeSharing(ctx context.Context, file *pb.File) error {
+	claims, ok := ClaimsFromContext(ctx)
+	if !ok {
+		return status.Error(codes.Unauthenticated, "caller is not authenticated")
+	}
+
+	if claims.Username == file.GetOwner().GetName() {
+		return nil
+	}
+
+	return status.Error(codes.PermissionDenied, "only the owner of the file can share it")
+}
+
+// canAccess reports whether the user owns the file or was granted at least the permission, WRITE includes READ
+func canAccess(username string, file *pb.File, permission pb.Permission) bool {
+	if username == file.GetOwner().GetName() {
+		return true
+	}
+
+	grant := findGrant(file, username)
+	return grant != nil && grant.GetPermission() >= permission
+}
+
+// findGrant returns the access the user was granted to the file, nil if there's none
+func findGrant(file *pb.File, username string) *pb.Grant {
+	for _, grant := range file.GetGrants() {
+		if grant.GetUser().GetName() == username {
+			return grant
+		}
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/Nextasy01/grpc-file-service/pb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Returns the files in the trash of a user
+func (server *FileServer) ListTrash(req *pb.ListTrashRequest, stream pb.FileService_ListTrashServer) error {
+	err := authorizeUser(stream.Context(), req.GetUser().GetName())
+	if err != nil {
+		return err
+	}
+
+	files := server.fileStore.ListTrash(req.GetUser().GetName())
+	for _, file := range files {
+		err := contextError(stream.Context())
+		if err != nil {
+			return err
+		}
+
+		err = stream.Send(&pb.ListFilesResponse{File: file})
+		if err != nil {
+			return status.Errorf(codes.Internal, "failed to send response: %v", err)
+		}
+	}
+
+	log.Println("Total files in trash returned:", len(files))
+	return nil
+}
+
+// Moves a file out of the trash
+func (server *FileServer) RestoreFromTrash(ctx context.Context, req *pb.RestoreFromTrashRequest) (*pb.RestoreFromTrashResponse, error) {
+	file := server.fileStore.FindTrashed(req.GetFileId())
+	if file == nil {
+		return nil, status.Errorf(codes.NotFound, "file with id \"%s\" is not in the trash", req.GetFileId())
+	}
+
+	err := authorizeOwner(ctx, file)
+	if err != nil {
+		return nil, err
+	}
+
+	restored, err := server.fileStore.Untrash(file.GetId())
+	if errors.Is(err, ErrNotFound) {
+		return nil, status.Errorf(codes.NotFound, "file with id \"%s\" is not in the trash", req.GetFileId())
+	}
+	if err != nil {
+		log.Println("Cannot restore file from the trash ", err)
+		return nil, status.Errorf(codes.Internal, "cannot restore file: %v", err)
+	}
+
+	log.Printf("Restored file %s from the trash", restored.GetTitle())
+	return &pb.RestoreFromTrashResponse{File: restored}, nil
+}
+
+// Permanently removes all files in the trash of a user
+func (server *FileServer) EmptyTrash(ctx context.Context, req *pb.EmptyTrashRequest) (*pb.EmptyTrashResponse, error) {
+	err := authorizeUser(ctx, req.GetUser().GetName())
+	if err != nil {
+		return nil, err
+	}
+
+	var removed uint32
+	for _, file := range server.fileStore.ListTrash(req.GetUser().GetName()) {
+		_, err := server.fileStore.Delete(file.GetId())
+		if errors.Is(err, ErrNotFound) {
+			continue // restored or purged in the meantime
+		}
+		if err != nil {
+			log.Println("Cannot empty the trash ", err)
+			return nil, status.Errorf(codes.Internal, "cannot delete file: %v", err)
+		}
+		removed++
+	}
+
+	log.Printf("Removed %d files from the trash of %s", removed, req.GetUser().GetName())
+	return &pb.EmptyTrashResponse{Removed: removed}, nil
+}
+
+// trash moves a file owned by the caller to the trash
+func (server *FileServer) trash(ctx context.Context, id string) (*pb.DeleteFileResponse, error) {
+	_, err := server.findOwnedFile(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := server.fileStore.Trash(id)
+	if errors.Is(err, ErrNotFound) {
+		return nil, status.Errorf(codes.NotFound, "file with id \"%s\" was not found", id)
+	}
+	if err != nil {
+		log.Println("Cannot move file to the trash ", err)
+		return nil, status.Errorf(codes.Internal, "cannot delete file: %v", err)
+	}
+
+	log.Printf("Moved file %s to the trash", file.GetTitle())
+	return &pb.DeleteFileResponse{File: file}, nil
+}
+
+// authorizeUser checks that the caller is the given user or an admin
+func authorizeUser(ctx context.Context, username string) error {
+	claims, ok := ClaimsFromContext(ctx)
+	if !ok {
+		return status.Error(codes.Unauthenticated, "caller is not authenticated")
+	}
+
+	if claims.Role == adminRole || claims.Username == username {
+		return nil
+	}
+
+	return status.Error(codes.PermissionDenied, "only the user can do this")
+}
@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/Nextasy01/grpc-file-service/pb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Returns all stored versions of a file
+func (server *FileServer) ListVersions(ctx context.Context, req *pb.ListVersionsRequest) (*pb.ListVersionsResponse, error) {
+	file, err := server.findAccessibleFile(ctx, req.GetFileId(), pb.Permission_READ)
+	if err != nil {
+		return nil, err
+	}
+
+	versions := server.fileStore.Versions(file.GetId())
+	if versions == nil {
+		return nil, status.Errorf(codes.NotFound, "file with id \"%s\" was not found", req.GetFileId())
+	}
+
+	for i, version := range versions {
+		versions[i] = hideGrants(ctx, version)
+	}
+	return &pb.ListVersionsResponse{Versions: versions}, nil
+}
+
+// Saves the contents of a previous version as the new current version of a file
+func (server *FileServer) RestoreVersion(ctx context.Context, req *pb.RestoreVersionRequest) (*pb.RestoreVersionResponse, error) {
+	file, err := server.findAccessibleFile(ctx, req.GetFileId(), pb.Permission_WRITE)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.GetVersion() == file.GetVersion() {
+		return nil, status.Errorf(codes.InvalidArgument, "version %d is already the current one", req.GetVersion())
+	}
+
+	restored, err := server.fileStore.RestoreVersion(file.GetId(), req.GetVersion())
+	if errors.Is(err, ErrNotFound) {
+		return nil, status.Errorf(codes.NotFound, "version %d of file \"%s\" was not found", req.GetVersion(), req.GetFileId())
+	}
+	if err != nil {
+		log.Println("Cannot restore version ", err)
+		return nil, status.Errorf(codes.Internal, "cannot restore version: %v", err)
+	}
+
+	log.Printf("Restored version %d of file %s as version %d", req.GetVersion(), file.GetId(), restored.GetVersion())
+	return &pb.RestoreVersionResponse{File: hideGrants(ctx, restored)}, nil
+}
+
+// Removes the previous versions of a file except the most recent ones
+func (server *FileServer) PruneVersions(ctx context.Context, req *pb.PruneVersionsRequest) (*pb.PruneVersionsResponse, error) {
+	file, err := server.findAccessibleFile(ctx, req.GetFileId(), pb.Permission_WRITE)
+	if err != nil {
+		return nil, err
+	}
+
+	removed, err := server.fileStore.PruneVersions(file.GetId(), int(req.GetKeep()))
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		log.Println("Cannot prune versions ", err)
+		return nil, status.Errorf(codes.Internal, "cannot prune versions: %v", err)
+	}
+
+	log.Printf("Removed %d versions of file %s", removed, file.GetId())
+	return &pb.PruneVersionsResponse{Removed: uint32(removed)}, nil
+}
+
+// findOwnedFile returns the file if it exists and the caller may change it
+func (server *FileServer) findOwnedFile(ctx context.Context, id string) (*pb.File, error) {
+	if id == "" {
+		return nil, status.Error(codes.InvalidArgument, "file id is required")
+	}
+
+	file := server.fileStore.Find(id)
+	if file == nil {
+		return nil, status.Errorf(codes.NotFound, "file with id \"%s\" was not found", id)
+	}
+
+	err := authorizeOwner(ctx, file)
+	if err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+// findVersion returns the file with the given version number
+func findVersion(versions []*pb.File, version uint32) *pb.File {
+	for _, file := range versions {
+		if file.GetVersion() == version {
+			return file
+		}
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package service
+
+import (
+	"errors"
+	"log"
+
+	"github.com/Nextasy01/grpc-file-service/pb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// Streams the changes of files as they happen until the client cancels the call
+func (server *FileServer) Watch(req *pb.WatchRequest, stream pb.FileService_WatchServer) error {
+	// without an owner only admins pass
+	err := authorizeUser(stream.Context(), req.GetOwner().GetName())
+	if err != nil {
+		return err
+	}
+
+	dir, err := cleanDirectory(req.GetFolder())
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	query := FileQuery{Labels: req.GetLabels()}
+	// the files shared with the user are watched along with the files of the user, as they are listed
+	watcher := req.GetOwner().GetName()
+	visible := func(file *pb.File) bool {
+		return watcher == "" || canAccess(watcher, file, pb.Permission_READ)
+	}
+
+	sequence := req.GetAfterSequence()
+	if sequence == 0 {
+		sequence = server.fileStore.LastEvent()
+	}
+	log.Printf("Watching files of user %s after event %d", req.GetOwner().GetName(), sequence)
+
+	for {
+		events, changed, err := server.fileStore.EventsSince(sequence)
+		switch {
+		case errors.Is(err, ErrEventsExpired):
+			return status.Errorf(codes.OutOfRange, "%v, list the files again and watch from now on", err)
+		case err != nil:
+			return status.Error(codes.InvalidArgument, err.Error())
+		}
+
+		for _, event := range events {
+			sequence = event.GetSequence()
+			if !visible(event.GetFile()) || !query.matches(event.GetFile()) || !watchedFolder(event, dir) {
+				continue
+			}
+
+			if file := hideGrants(stream.Context(), event.GetFile()); file != event.GetFile() {
+				event = proto.Clone(event).(*pb.FileEvent)
+				event.File = file
+			}
+			err := stream.Send(event)
+			if err != nil {
+				return status.Errorf(codes.Internal, "failed to send event: %v", err)
+			}
+		}
+
+		select {
+		case <-changed:
+		case <-stream.Context().Done():
+			return contextError(stream.Context())
+		}
+	}
+}
+
+// watchedFolder reports whether the event concerns the folder dir, files moved out of it included
+func watchedFolder(event *pb.FileEvent, dir string) bool {
+	if inFolder(event.GetFile().GetPath(), dir) {
+		return true
+	}
+	return event.GetPreviousPath() != "" && inFolder(parentFolder(event.GetPreviousPath()), dir)
+}
@@ -1,90 +1,885 @@
 package service
 
 import (
-	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"hash"
+	"io"
+	"log"
 	"os"
 	"path/filepath"
 	"sync"
+	"time"
 
 	"github.com/Nextasy01/grpc-file-service/pb"
 	"github.com/google/uuid"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 // ErrAlreadyExists is returned when a record with the same ID already exists in the store
 var ErrAlreadyExists = errors.New("record already exists")
 
+// ErrNotFound is returned when a file with the given ID doesn't exist in the store
+var ErrNotFound = errors.New("record not found")
+
 type FileStore interface {
-	Save(file *pb.File, data bytes.Buffer) error
+	// Create starts saving a new file. The file becomes visible only after the writer is committed
+	Create(file *pb.File) (FileWriter, error)
+	// Open returns the contents of a saved file
+	Open(id string) (io.ReadSeekCloser, error)
 	List(username string) []*pb.File
 	Find(filename string) *pb.File
+	// Delete removes the file metadata along with its contents
+	Delete(id string) (*pb.File, error)
+	// Usage returns how much storage the files of a user take
+	Usage(username string) Usage
+	// FindByPath returns the file of a user with the given path relative to the root of the user
+	FindByPath(username, name string) *pb.File
+	// Copy saves file as a new file with the contents of the file with the given ID, the contents are stored only once.
+	// The copy can't have the path of another file of its owner
+	Copy(id string, file *pb.File) error
+	// CreateVersion starts saving new contents of an existing file,
+	// the current contents are kept as a previous version when the writer is committed
+	CreateVersion(id string, file *pb.File) (FileWriter, error)
+	// Versions returns all versions of a file, the current one is the last
+	Versions(id string) []*pb.File
+	// OpenVersion returns the contents of a version of a file, version 0 means the current one
+	OpenVersion(id string, version uint32) (io.ReadSeekCloser, error)
+	// RestoreVersion saves the contents of a previous version as the new current version
+	RestoreVersion(id string, version uint32) (*pb.File, error)
+	// PruneVersions removes all but the keep most recent previous versions and returns how many were removed
+	PruneVersions(id string, keep int) (int, error)
+	// Trash moves the file to the trash of its owner, trashed files are hidden from the other methods
+	Trash(id string) (*pb.File, error)
+	// Untrash moves the file out of the trash
+	Untrash(id string) (*pb.File, error)
+	// FindTrashed returns a file which is in the trash
+	FindTrashed(id string) *pb.File
+	// ListTrash returns the files in the trash of a user
+	ListTrash(username string) []*pb.File
+	// PurgeTrash removes the files moved to the trash before the given time
+	PurgeTrash(before time.Time) ([]*pb.File, error)
+	// CreateFolder creates an empty folder, its parents don't have to exist
+	CreateFolder(folder *pb.Folder) error
+	// Folders returns the folders of a user, including the ones which only exist because files are stored in them
+	Folders(username string) []*pb.Folder
+	// Move moves a file into the folder dir and renames it unless title is empty
+	Move(id, dir, title string) (*pb.File, error)
+	// MoveFolder moves a folder with all files and folders in it and returns the number of moved files
+	MoveFolder(username, from, to string) (int, error)
+	// RemoveFolders removes a folder and the folders in it, the files have to be removed before
+	RemoveFolders(username, path string) error
+	// UpdateMetadata changes the labels and tags of a file by calling update with a copy of the file,
+	// nothing is changed if update returns an error
+	UpdateMetadata(id string, update func(file *pb.File) error) (*pb.File, error)
+	// Search returns the files matching the query ordered by their path, files in the trash aren't searched
+	Search(query FileQuery) []*pb.File
+	// LastEvent returns the sequence number of the latest change of the files
+	LastEvent() uint64
+	// EventsSince returns the changes of the files after the given sequence number,
+	// oldest first, and a channel which is closed when the next change happens
+	EventsSince(sequence uint64) ([]*pb.FileEvent, <-chan struct{}, error)
+	// SaveUpload records the offset of a resumable upload so it can be continued after a restart,
+	// the writer of the session has to be created by the store and synced up to the offset
+	SaveUpload(session *UploadSession) error
+	// RemoveUpload forgets a resumable upload, its writer is committed or aborted by the caller
+	RemoveUpload(id string) error
+	// Uploads returns the saved resumable uploads, their writers continue from the saved offsets
+	Uploads() []*UploadSession
+}
+
+// Usage is the amount of storage used by a user
+type Usage struct {
+	Bytes uint64
+	Files uint64
+}
+
+// FileWriter receives the contents of a file while it is being uploaded
+type FileWriter interface {
+	io.Writer
+	// Checksum returns the hex encoded SHA-256 of the contents written so far
+	Checksum() string
+	// Sync makes sure the contents written so far are stored durably
+	Sync() error
+	// Truncate discards everything written after the first size bytes
+	Truncate(size uint64) error
+	// Commit atomically stores the written contents and saves the file metadata
+	Commit() error
+	// Abort discards everything written so far
+	Abort() error
 }
 
+const uploadFilePattern = ".upload-*"
+
 type InMemoryFileStore struct {
 	mutex      sync.RWMutex
 	fileFolder string
 	data       map[string]*pb.File
+	blobs      map[string]string         // file ID -> name of the blob with its contents
+	versions   map[string][]fileVersion  // file ID -> previous versions, oldest first
+	refs       map[string]int            // blob name -> number of files and versions referring to it
+	usage      map[string]Usage          // owner name -> storage used by the owner
+	folders    map[string]*pb.Folder     // explicitly created folders, see folderKey
+	index      *fileIndex                // finds the files matching a search
+	events     *eventFeed                // recent changes of the files for watchers
+	uploads    map[string]*UploadSession // resumable uploads by session ID, see SaveUpload
+	dedup      bool                      // blobs are named by the SHA-256 of their contents and shared
+	journal    *fileJournal              // only set when metadata is persisted, see DiskFileStore
+}
+
+// fileVersion is a previous version of a file
+type fileVersion struct {
+	file *pb.File
+	blob string
 }
 
 func NewInMemoryFileStore(dir string) *InMemoryFileStore {
 	return &InMemoryFileStore{
 		data:       make(map[string]*pb.File),
+		blobs:      make(map[string]string),
+		versions:   make(map[string][]fileVersion),
+		refs:       make(map[string]int),
+		usage:      make(map[string]Usage),
+		folders:    make(map[string]*pb.Folder),
+		index:      newFileIndex(),
+		events:     newEventFeed(),
+		uploads:    make(map[string]*UploadSession),
 		fileFolder: dir,
 	}
 }
 
-func (store *InMemoryFileStore) Save(file *pb.File, data bytes.Buffer) error {
+// EnableDeduplication switches the store to content-addressed storage:
+// files with identical contents share a single blob, which is removed along with the last file using it
+func (store *InMemoryFileStore) EnableDeduplication() {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
 
+	store.dedup = true
+}
+
+// Save stores a file with the contents read from data
+func (store *InMemoryFileStore) Save(file *pb.File, data io.Reader) error {
+	writer, err := store.Create(file)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(writer, data)
+	if err != nil {
+		writer.Abort()
+		return err
+	}
+
+	return writer.Commit()
+}
+
+func (store *InMemoryFileStore) Create(file *pb.File) (FileWriter, error) {
+	fileId, _ := uuid.NewRandom()
+	file.Id = fileId.String()
+	file.Version = 1
+
+	return store.newWriter(file, "")
+}
+
+func (store *InMemoryFileStore) Copy(id string, file *pb.File) error {
+	dir, err := cleanDirectory(file.GetPath())
+	if err != nil {
+		return err
+	}
 	fileId, _ := uuid.NewRandom()
-	fileType := filepath.Ext(file.GetTitle()) //strings.Split(file.GetTitle(), ".")[1]
+	file.Id = fileId.String()
+	file.Version = 1
+	file.Path = dir
+	file.Title = filepath.Base(file.GetTitle())
+	file.Extension = fileExtension(file.GetTitle())
+	file.CreatedAt = timestamppb.Now()
+	file.UpdatedAt = file.GetCreatedAt()
+
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	original, ok := store.find(id)
+	if !ok {
+		return ErrNotFound
+	}
+	if store.findByPath(file.GetOwner().GetName(), fullPath(file)) != nil {
+		return fmt.Errorf("%w: %q", ErrAlreadyExists, fullPath(file))
+	}
+	file.Size = original.GetSize()
+	file.Checksum = original.GetChecksum()
+	file.ContentType = original.GetContentType()
+
+	// the copy shares the blob of the original
+	blob := store.blobs[id]
+	store.refs[blob]++
+	err = store.add(file, blob)
+	if err != nil {
+		store.release(blob)
+	}
+	return err
+}
 
+func (store *InMemoryFileStore) CreateVersion(id string, file *pb.File) (FileWriter, error) {
+	if store.Find(id) == nil {
+		return nil, ErrNotFound
+	}
+
+	return store.newWriter(file, id)
+}
+
+func (store *InMemoryFileStore) newWriter(file *pb.File, replaces string) (FileWriter, error) {
 	// create folder/directory if not exists
 	if _, err := os.Stat(store.fileFolder); errors.Is(err, os.ErrNotExist) {
 		err := os.Mkdir(store.fileFolder, os.ModePerm)
 		if err != nil {
-			return err
+			return nil, err
 		}
 	}
 
-	fileName := fmt.Sprintf("%s%s", fileId, fileType)
-	filePath := filepath.Join(store.fileFolder, fileName)
-	// filePath := fmt.Sprintf("%s/%s%s", store.fileFolder, fileId, fileType)
-	file.Id = fileId.String()
+	dir, err := cleanDirectory(file.GetPath())
+	if err != nil {
+		return nil, err
+	}
+	file.Path = dir
 	file.Title = filepath.Base(file.GetTitle())
+	file.Extension = fileExtension(file.GetTitle())
 
-	newFile, err := os.Create(filePath)
+	temp, err := os.CreateTemp(store.fileFolder, uploadFilePattern)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	return &fileWriter{store: store, file: file, replaces: replaces, temp: temp, hash: sha256.New()}, nil
+}
+
+func (store *InMemoryFileStore) Open(id string) (io.ReadSeekCloser, error) {
+	return store.OpenVersion(id, 0)
+}
+
+func (store *InMemoryFileStore) OpenVersion(id string, version uint32) (io.ReadSeekCloser, error) {
+	store.mutex.RLock()
+	blob, ok := store.versionBlob(id, version)
+	store.mutex.RUnlock()
+
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return os.Open(filepath.Join(store.fileFolder, blob))
+}
+
+func (store *InMemoryFileStore) versionBlob(id string, version uint32) (string, bool) {
+	file, ok := store.find(id)
+	if !ok {
+		return "", false
+	}
+	if version == 0 || version == file.GetVersion() {
+		return store.blobs[id], true
+	}
+
+	for _, previous := range store.versions[id] {
+		if previous.file.GetVersion() == version {
+			return previous.blob, true
+		}
+	}
+	return "", false
+}
+
+func (store *InMemoryFileStore) Versions(id string) []*pb.File {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	file, ok := store.find(id)
+	if !ok {
+		return nil
+	}
+
+	versions := make([]*pb.File, 0, len(store.versions[id])+1)
+	for _, previous := range store.versions[id] {
+		versions = append(versions, previous.file)
+	}
+	return append(versions, file)
+}
+
+func (store *InMemoryFileStore) RestoreVersion(id string, version uint32) (*pb.File, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	current, ok := store.find(id)
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	var restored fileVersion
+	for _, previous := range store.versions[id] {
+		if previous.file.GetVersion() == version {
+			restored = previous
+			break
+		}
+	}
+	if restored.file == nil {
+		return nil, ErrNotFound
 	}
-	defer newFile.Close()
 
-	_, err = data.WriteTo(newFile)
+	file := proto.Clone(restored.file).(*pb.File)
+	file.Version = nextVersion(current)
+	file.UpdatedAt = timestamppb.Now()
+	file.Labels = current.GetLabels()
+	file.Tags = current.GetTags()
+	file.Grants = current.GetGrants()
+
+	// the restored version shares the blob of the old one
+	store.refs[restored.blob]++
+	err := store.replace(file, restored.blob)
 	if err != nil {
-		return err
+		store.refs[restored.blob]--
+		return nil, err
 	}
 
+	return file, nil
+}
+
+func (store *InMemoryFileStore) PruneVersions(id string, keep int) (int, error) {
 	store.mutex.Lock()
 	defer store.mutex.Unlock()
 
-	store.data[fileId.String()] = file
+	if _, ok := store.find(id); !ok {
+		return 0, ErrNotFound
+	}
+
+	removed := 0
+	for len(store.versions[id]) > keep {
+		oldest := store.versions[id][0]
+		if store.journal != nil {
+			err := store.journal.prune(id, oldest.file.GetVersion())
+			if err != nil {
+				return removed, err
+			}
+		}
+
+		store.versions[id] = store.versions[id][1:]
+		store.unaccountVersion(oldest.file)
+		store.release(oldest.blob)
+		removed++
+	}
+
+	if len(store.versions[id]) == 0 {
+		delete(store.versions, id)
+	}
+	return removed, nil
+}
+
+// replace makes file the current version and keeps the replaced one as a previous version.
+// The caller holds the lock and a reference to the blob
+func (store *InMemoryFileStore) replace(file *pb.File, blob string) error {
+	id := file.GetId()
+	current := store.data[id]
+
+	if store.journal != nil {
+		err := store.journal.put(file, blob, true)
+		if err != nil {
+			return err
+		}
+	}
+
+	store.versions[id] = append(store.versions[id], fileVersion{current, store.blobs[id]})
+	store.unaccount(current)
+	store.accountVersion(current)
+
+	store.index.remove(current)
+	store.data[id] = file
+	store.blobs[id] = blob
+	store.account(file)
+	store.index.add(file)
+	store.events.publish(pb.FileEventType_UPDATED, file, "")
 	return nil
 }
 
+// nextVersion returns the number of the version replacing the file
+func nextVersion(file *pb.File) uint32 {
+	if file.GetVersion() == 0 {
+		return 2 // saved before versions were numbered
+	}
+	return file.GetVersion() + 1
+}
+
+func (store *InMemoryFileStore) FindByPath(username, name string) *pb.File {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	return store.findByPath(username, name)
+}
+
+func (store *InMemoryFileStore) findByPath(username, name string) *pb.File {
+	var found *pb.File
+	for _, file := range store.data {
+		if file.GetOwner().GetName() != username || fullPath(file) != name || isTrashed(file) {
+			continue
+		}
+		// prefer the most recent one if there are duplicates from before versioning
+		if found == nil || file.GetCreatedAt().AsTime().After(found.GetCreatedAt().AsTime()) {
+			found = file
+		}
+	}
+	return found
+}
+
 func (store *InMemoryFileStore) Find(filename string) *pb.File {
-	file, ok := store.data[filename]
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	file, ok := store.find(filename)
 	if ok {
 		return file
 	}
 	return nil
 }
 
+// find returns the file if it exists and is not in the trash
+func (store *InMemoryFileStore) find(id string) (*pb.File, bool) {
+	file, ok := store.data[id]
+	if !ok || isTrashed(file) {
+		return nil, false
+	}
+	return file, true
+}
+
+func isTrashed(file *pb.File) bool {
+	return file.GetDeletedAt() != nil
+}
+
 func (store *InMemoryFileStore) List(username string) []*pb.File {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
 	files := make([]*pb.File, 0)
 	for _, v := range store.data {
-		if v.Owner.Name == username {
+		if v.Owner.Name == username && !isTrashed(v) {
 			files = append(files, v)
 		}
 	}
 	return files
 }
+
+func (store *InMemoryFileStore) Trash(id string) (*pb.File, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	file, ok := store.find(id)
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	trashed := proto.Clone(file).(*pb.File)
+	trashed.DeletedAt = timestamppb.Now()
+	return trashed, store.update(trashed, pb.FileEventType_DELETED)
+}
+
+func (store *InMemoryFileStore) Untrash(id string) (*pb.File, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	file, ok := store.data[id]
+	if !ok || !isTrashed(file) {
+		return nil, ErrNotFound
+	}
+
+	restored := proto.Clone(file).(*pb.File)
+	restored.DeletedAt = nil
+	return restored, store.update(restored, pb.FileEventType_CREATED)
+}
+
+func (store *InMemoryFileStore) UpdateMetadata(id string, update func(file *pb.File) error) (*pb.File, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	file, ok := store.find(id)
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	updated := proto.Clone(file).(*pb.File)
+	err := update(updated)
+	if err != nil {
+		return nil, err
+	}
+	updated.UpdatedAt = timestamppb.Now()
+	return updated, store.update(updated, pb.FileEventType_UPDATED)
+}
+
+// update replaces the metadata of the current version of a file and publishes the change as an event of the given type.
+// The caller holds the lock
+func (store *InMemoryFileStore) update(file *pb.File, eventType pb.FileEventType) error {
+	if store.journal != nil {
+		err := store.journal.put(file, store.blobs[file.GetId()], false)
+		if err != nil {
+			return err
+		}
+	}
+
+	previousPath := ""
+	if current, ok := store.data[file.GetId()]; ok {
+		store.index.remove(current)
+		previousPath = fullPath(current)
+	}
+	store.data[file.GetId()] = file
+	store.index.add(file)
+
+	if eventType != pb.FileEventType_MOVED {
+		previousPath = ""
+	}
+	store.events.publish(eventType, file, previousPath)
+	return nil
+}
+
+func (store *InMemoryFileStore) LastEvent() uint64 {
+	return store.events.last()
+}
+
+func (store *InMemoryFileStore) EventsSince(sequence uint64) ([]*pb.FileEvent, <-chan struct{}, error) {
+	return store.events.since(sequence)
+}
+
+func (store *InMemoryFileStore) FindTrashed(id string) *pb.File {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	file, ok := store.data[id]
+	if !ok || !isTrashed(file) {
+		return nil
+	}
+	return file
+}
+
+func (store *InMemoryFileStore) ListTrash(username string) []*pb.File {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	files := make([]*pb.File, 0)
+	for _, file := range store.data {
+		if file.GetOwner().GetName() == username && isTrashed(file) {
+			files = append(files, file)
+		}
+	}
+	return files
+}
+
+func (store *InMemoryFileStore) PurgeTrash(before time.Time) ([]*pb.File, error) {
+	store.mutex.RLock()
+	expired := make([]string, 0)
+	for id, file := range store.data {
+		if isTrashed(file) && file.GetDeletedAt().AsTime().Before(before) {
+			expired = append(expired, id)
+		}
+	}
+	store.mutex.RUnlock()
+
+	purged := make([]*pb.File, 0, len(expired))
+	for _, id := range expired {
+		file, err := store.Delete(id)
+		if errors.Is(err, ErrNotFound) {
+			continue // removed in the meantime
+		}
+		if err != nil {
+			return purged, err
+		}
+		purged = append(purged, file)
+	}
+	return purged, nil
+}
+
+func (store *InMemoryFileStore) Delete(id string) (*pb.File, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	file, ok := store.data[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	if store.journal != nil {
+		err := store.journal.delete(id)
+		if err != nil {
+			return nil, err
+		}
+	}
+	delete(store.data, id)
+	store.unaccount(file)
+	store.index.remove(file)
+	if !isTrashed(file) {
+		// files in the trash were reported deleted when they were moved there
+		store.events.publish(pb.FileEventType_DELETED, file, "")
+	}
+
+	blob := store.blobs[id]
+	delete(store.blobs, id)
+	store.release(blob)
+
+	for _, previous := range store.versions[id] {
+		store.unaccountVersion(previous.file)
+		store.release(previous.blob)
+	}
+	delete(store.versions, id)
+
+	return file, nil
+}
+
+func (store *InMemoryFileStore) Usage(username string) Usage {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	return store.usage[username]
+}
+
+// account adds the file to the storage used by its owner
+func (store *InMemoryFileStore) account(file *pb.File) {
+	store.adjustUsage(file.GetOwner().GetName(), file.GetSize(), 1, true)
+}
+
+// unaccount removes the file from the storage used by its owner
+func (store *InMemoryFileStore) unaccount(file *pb.File) {
+	store.adjustUsage(file.GetOwner().GetName(), file.GetSize(), 1, false)
+}
+
+// accountVersion adds a previous version to the storage used by its owner, versions are not counted as files
+func (store *InMemoryFileStore) accountVersion(file *pb.File) {
+	store.adjustUsage(file.GetOwner().GetName(), file.GetSize(), 0, true)
+}
+
+// unaccountVersion removes a previous version from the storage used by its owner
+func (store *InMemoryFileStore) unaccountVersion(file *pb.File) {
+	store.adjustUsage(file.GetOwner().GetName(), file.GetSize(), 0, false)
+}
+
+func (store *InMemoryFileStore) adjustUsage(owner string, bytes, files uint64, add bool) {
+	usage := store.usage[owner]
+	if add {
+		usage.Bytes += bytes
+		usage.Files += files
+	} else {
+		usage.Bytes -= bytes
+		usage.Files -= files
+	}
+
+	if usage == (Usage{}) {
+		delete(store.usage, owner)
+		return
+	}
+	store.usage[owner] = usage
+}
+
+// release drops a reference to the blob and removes it when it's no longer used
+func (store *InMemoryFileStore) release(blob string) {
+	store.refs[blob]--
+	if store.refs[blob] > 0 {
+		return
+	}
+	delete(store.refs, blob)
+
+	// the metadata is already gone, a leftover blob is cleaned up on the next start
+	err := os.Remove(filepath.Join(store.fileFolder, blob))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Printf("Cannot remove blob %s: %v", blob, err)
+	}
+}
+
+// blobName returns the name of the blob for a file when deduplication is disabled
+func blobName(file *pb.File) string {
+	if file.GetVersion() > 1 {
+		return fmt.Sprintf("%s.v%d%s", file.GetId(), file.GetVersion(), file.GetExtension())
+	}
+	return fmt.Sprintf("%s%s", file.GetId(), file.GetExtension())
+}
+
+// fileWriter writes the contents to a temporary file which is renamed into place on commit
+type fileWriter struct {
+	store    *InMemoryFileStore
+	file     *pb.File
+	replaces string // ID of the file getting a new version
+	temp     *os.File
+	hash     hash.Hash
+	head     []byte // first bytes of the contents to detect the content type
+	size     uint64
+	done     bool
+}
+
+func (writer *fileWriter) Write(p []byte) (int, error) {
+	n, err := writer.temp.Write(p)
+	writer.track(p[:n])
+	return n, err
+}
+
+// track adds the written bytes to the checksum, size and head of the contents
+func (writer *fileWriter) track(p []byte) {
+	writer.hash.Write(p)
+	if missing := sniffLen - len(writer.head); missing > 0 {
+		if missing > len(p) {
+			missing = len(p)
+		}
+		writer.head = append(writer.head, p[:missing]...)
+	}
+	writer.size += uint64(len(p))
+}
+
+func (writer *fileWriter) Sync() error {
+	return writer.temp.Sync()
+}
+
+func (writer *fileWriter) Truncate(size uint64) error {
+	if size > writer.size {
+		return fmt.Errorf("cannot truncate %d bytes to %d bytes", writer.size, size)
+	}
+	if size == writer.size {
+		return nil
+	}
+	return writer.rewind(size)
+}
+
+// rewind truncates the temporary file to size and computes the checksum of the remaining contents again
+func (writer *fileWriter) rewind(size uint64) error {
+	err := writer.temp.Truncate(int64(size))
+	if err != nil {
+		return err
+	}
+	_, err = writer.temp.Seek(int64(size), io.SeekStart)
+	if err != nil {
+		return err
+	}
+
+	writer.hash.Reset()
+	writer.head = nil
+	writer.size = 0
+
+	contents := io.NewSectionReader(writer.temp, 0, int64(size))
+	buffer := make([]byte, 32<<10)
+	for {
+		n, err := contents.Read(buffer)
+		writer.track(buffer[:n])
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if writer.size != size {
+		return fmt.Errorf("expected %d bytes of contents, found %d", size, writer.size)
+	}
+	return nil
+}
+
+func (writer *fileWriter) Checksum() string {
+	return hex.EncodeToString(writer.hash.Sum(nil))
+}
+
+func (writer *fileWriter) Commit() error {
+	if writer.done {
+		return errors.New("file is already committed or aborted")
+	}
+	writer.done = true
+
+	store := writer.store
+	tempPath := writer.temp.Name()
+	defer os.Remove(tempPath) // no-op once the temporary file is renamed
+
+	var err error
+	if store.journal != nil {
+		// the contents have to reach the disk before the journal references them
+		err = writer.temp.Sync()
+	}
+	if closeErr := writer.temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	writer.file.Size = writer.size
+	writer.file.Checksum = writer.Checksum()
+	writer.file.ContentType = detectContentType(writer.head, writer.file.GetExtension())
+
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	if writer.replaces != "" {
+		current, ok := store.find(writer.replaces)
+		if !ok {
+			return ErrNotFound
+		}
+		writer.file.Id = current.GetId()
+		writer.file.Owner = current.GetOwner()
+		writer.file.CreatedAt = current.GetCreatedAt()
+		writer.file.UpdatedAt = timestamppb.Now()
+		writer.file.Version = nextVersion(current)
+		// labels and tags belong to the file, the upload only replaces them if it has any
+		if len(writer.file.GetLabels()) == 0 {
+			writer.file.Labels = current.GetLabels()
+		}
+		if len(writer.file.GetTags()) == 0 {
+			writer.file.Tags = current.GetTags()
+		}
+		writer.file.Grants = current.GetGrants()
+	} else {
+		// searching and sorting by time rely on it, so it's never taken from the client
+		writer.file.CreatedAt = timestamppb.Now()
+		writer.file.UpdatedAt = writer.file.GetCreatedAt()
+	}
+
+	blob := blobName(writer.file)
+	if store.dedup {
+		blob = writer.file.GetChecksum()
+	}
+
+	// with deduplication the same contents may be stored already
+	if store.refs[blob] == 0 {
+		err = os.Rename(tempPath, filepath.Join(store.fileFolder, blob))
+		if err != nil {
+			return err
+		}
+	}
+	store.refs[blob]++
+
+	if writer.replaces != "" {
+		err = store.replace(writer.file, blob)
+		if err != nil {
+			store.release(blob)
+		}
+		return err
+	}
+
+	err = store.add(writer.file, blob)
+	if err != nil {
+		store.release(blob)
+	}
+	return err
+}
+
+// add saves a new file with its contents in the blob. The caller holds the lock and a reference to the blob
+func (store *InMemoryFileStore) add(file *pb.File, blob string) error {
+	if store.journal != nil {
+		err := store.journal.put(file, blob, false)
+		if err != nil {
+			return err
+		}
+	}
+
+	store.data[file.GetId()] = file
+	store.blobs[file.GetId()] = blob
+	store.account(file)
+	store.index.add(file)
+	store.events.publish(pb.FileEventType_CREATED, file, "")
+	return nil
+}
+
+func (writer *fileWriter) Abort() error {
+	if writer.done {
+		return nil
+	}
+	writer.done = true
+
+	writer.temp.Close()
+	return os.Remove(writer.temp.Name())
+}
@@ -0,0 +1,261 @@
+package service
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/Nextasy01/grpc-file-service/pb"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func (store *InMemoryFileStore) CreateFolder(folder *pb.Folder) error {
+	dir, err := cleanDirectory(folder.GetPath())
+	if err != nil {
+		return err
+	}
+	if dir == "" {
+		return fmt.Errorf("%w: the root folder always exists", ErrAlreadyExists)
+	}
+
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	owner := folder.GetOwner().GetName()
+	if store.folderExists(owner, dir) {
+		return ErrAlreadyExists
+	}
+
+	folder.Path = dir
+	folder.CreatedAt = timestamppb.Now()
+	if store.journal != nil {
+		err := store.journal.mkdir(folder)
+		if err != nil {
+			return err
+		}
+	}
+
+	store.folders[folderKey(owner, dir)] = folder
+	return nil
+}
+
+func (store *InMemoryFileStore) Folders(username string) []*pb.Folder {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	folders := make(map[string]*pb.Folder)
+	for _, folder := range store.folders {
+		if folder.GetOwner().GetName() == username {
+			folders[folder.GetPath()] = folder
+		}
+	}
+
+	// folders which only exist because files or other folders are stored in them
+	implied := func(dir string, owner *pb.Owner) {
+		for ; dir != "." && dir != ""; dir = path.Dir(dir) {
+			if _, ok := folders[dir]; !ok {
+				folders[dir] = &pb.Folder{Path: dir, Owner: owner}
+			}
+		}
+	}
+	for _, folder := range store.folders {
+		if folder.GetOwner().GetName() == username {
+			implied(parentFolder(folder.GetPath()), folder.GetOwner())
+		}
+	}
+	for _, file := range store.data {
+		if file.GetOwner().GetName() == username && !isTrashed(file) {
+			implied(file.GetPath(), file.GetOwner())
+		}
+	}
+
+	list := make([]*pb.Folder, 0, len(folders))
+	for _, folder := range folders {
+		list = append(list, folder)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].GetPath() < list[j].GetPath()
+	})
+	return list
+}
+
+func (store *InMemoryFileStore) Move(id, dir, title string) (*pb.File, error) {
+	dir, err := cleanDirectory(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	file, ok := store.find(id)
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	moved := proto.Clone(file).(*pb.File)
+	moved.Path = dir
+	if title != "" {
+		moved.Title = path.Base(title)
+		moved.Extension = fileExtension(moved.Title)
+	}
+	// versions are uploaded to the file found by its path, so two files can't share it
+	existing := store.findByPath(moved.GetOwner().GetName(), fullPath(moved))
+	if existing != nil && existing.GetId() != id {
+		return nil, fmt.Errorf("%w: %q", ErrAlreadyExists, fullPath(moved))
+	}
+	return moved, store.update(moved, pb.FileEventType_MOVED)
+}
+
+func (store *InMemoryFileStore) MoveFolder(username, from, to string) (int, error) {
+	from, err := cleanDirectory(from)
+	if err != nil {
+		return 0, err
+	}
+	to, err = cleanDirectory(to)
+	if err != nil {
+		return 0, err
+	}
+	if from == "" || to == "" {
+		return 0, fmt.Errorf("%w: the root folder can't be moved or replaced", ErrInvalidPath)
+	}
+	if inFolder(to, from) {
+		return 0, fmt.Errorf("%w: %q can't be moved into itself", ErrInvalidPath, from)
+	}
+
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	if !store.folderExists(username, from) {
+		return 0, ErrNotFound
+	}
+	if store.folderExists(username, to) || store.findByPath(username, to) != nil {
+		return 0, ErrAlreadyExists
+	}
+	for _, file := range store.data {
+		if file.GetOwner().GetName() != username || isTrashed(file) || !inFolder(file.GetPath(), from) {
+			continue
+		}
+		movedPath := path.Join(to+strings.TrimPrefix(file.GetPath(), from), file.GetTitle())
+		existing := store.findByPath(username, movedPath)
+		if existing != nil && existing.GetId() != file.GetId() {
+			return 0, fmt.Errorf("%w: %q", ErrAlreadyExists, movedPath)
+		}
+	}
+
+	if store.journal != nil {
+		err := store.journal.move(username, from, to)
+		if err != nil {
+			return 0, err
+		}
+	}
+	previousPaths := make(map[string]string)
+	for id, file := range store.data {
+		if file.GetOwner().GetName() == username && !isTrashed(file) && inFolder(file.GetPath(), from) {
+			previousPaths[id] = fullPath(file)
+		}
+	}
+
+	moved := moveTree(store.data, store.versions, store.folders, username, from, to)
+	for id, previousPath := range previousPaths {
+		store.events.publish(pb.FileEventType_MOVED, store.data[id], previousPath)
+	}
+	return moved, nil
+}
+
+func (store *InMemoryFileStore) RemoveFolders(username, dir string) error {
+	dir, err := cleanDirectory(dir)
+	if err != nil {
+		return err
+	}
+
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	if store.journal != nil {
+		err := store.journal.rmdir(username, dir)
+		if err != nil {
+			return err
+		}
+	}
+	removeFolders(store.folders, username, dir)
+	return nil
+}
+
+// folderExists reports whether the folder was created or files are stored in it. The caller holds the lock
+func (store *InMemoryFileStore) folderExists(owner, dir string) bool {
+	if _, ok := store.folders[folderKey(owner, dir)]; ok {
+		return true
+	}
+	for _, file := range store.data {
+		if file.GetOwner().GetName() == owner && !isTrashed(file) && inFolder(file.GetPath(), dir) {
+			return true
+		}
+	}
+	for _, folder := range store.folders {
+		if folder.GetOwner().GetName() == owner && inFolder(folder.GetPath(), dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// folderKey identifies a folder of a user in the folder maps
+func folderKey(owner, dir string) string {
+	return owner + "\x00" + dir
+}
+
+// inFolder reports whether dir is the folder itself or inside of it, every path is inside the root
+func inFolder(dir, folder string) bool {
+	return folder == "" || dir == folder || strings.HasPrefix(dir, folder+"/")
+}
+
+// moveTree moves the files and folders of owner in the folder from to the path to and returns the number of moved files.
+// Moved entries are replaced by copies since the old ones may still be read by callers
+func moveTree(files map[string]*pb.File, versions map[string][]fileVersion, folders map[string]*pb.Folder, owner, from, to string) int {
+	movedPath := func(dir string) string {
+		return to + strings.TrimPrefix(dir, from)
+	}
+
+	moved := 0
+	for id, file := range files {
+		if file.GetOwner().GetName() != owner || !inFolder(file.GetPath(), from) {
+			continue
+		}
+
+		clone := proto.Clone(file).(*pb.File)
+		clone.Path = movedPath(file.GetPath())
+		files[id] = clone
+		for i, version := range versions[id] {
+			clone := proto.Clone(version.file).(*pb.File)
+			clone.Path = movedPath(version.file.GetPath())
+			versions[id][i].file = clone
+		}
+		moved++
+	}
+
+	movedFolders := make([]*pb.Folder, 0)
+	for key, folder := range folders {
+		if folder.GetOwner().GetName() == owner && inFolder(folder.GetPath(), from) {
+			delete(folders, key)
+			clone := proto.Clone(folder).(*pb.Folder)
+			clone.Path = movedPath(folder.GetPath())
+			movedFolders = append(movedFolders, clone)
+		}
+	}
+	for _, folder := range movedFolders {
+		folders[folderKey(owner, folder.GetPath())] = folder
+	}
+	return moved
+}
+
+// removeFolders removes the folder of owner and the folders in it
+func removeFolders(folders map[string]*pb.Folder, owner, dir string) {
+	for key, folder := range folders {
+		if folder.GetOwner().GetName() == owner && inFolder(folder.GetPath(), dir) {
+			delete(folders, key)
+		}
+	}
+}
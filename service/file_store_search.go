@@ -0,0 +1,133 @@
+package service
+
+import (
+	"math"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Nextasy01/grpc-file-service/pb"
+)
+
+// FileQuery selects the files returned by Search, files have to match every field which is set
+type FileQuery struct {
+	Owner string
+	// Name is a glob pattern matched against the whole name of the file, without any of the
+	// characters *?[ the name only has to contain it. Case insensitive
+	Name string
+	// MinSize and MaxSize include the bounds, MaxSize 0 means no upper bound
+	MinSize uint64
+	MaxSize uint64
+	Created TimeSpan
+	Updated TimeSpan
+	// ContentType is a media type like "image/png" or a top-level type like "image/"
+	ContentType string
+	// Labels the file has to have, an empty value matches any value of the label
+	Labels map[string]string
+	// SharedWith is a user the file has to be shared with
+	SharedWith string
+}
+
+// TimeSpan is the time from Start up to but not including End, a zero bound is open
+type TimeSpan struct {
+	Start time.Time
+	End   time.Time
+}
+
+func (span TimeSpan) IsZero() bool {
+	return span.Start.IsZero() && span.End.IsZero()
+}
+
+func (span TimeSpan) contains(t time.Time) bool {
+	return (span.Start.IsZero() || !t.Before(span.Start)) && (span.End.IsZero() || t.Before(span.End))
+}
+
+// nanos returns the span as the range of Unix times in nanoseconds including both bounds
+func (span TimeSpan) nanos() (min, max int64) {
+	min, max = math.MinInt64, math.MaxInt64
+	if !span.Start.IsZero() {
+		min = span.Start.UnixNano()
+	}
+	if !span.End.IsZero() {
+		max = span.End.UnixNano() - 1
+	}
+	return min, max
+}
+
+func (store *InMemoryFileStore) Search(query FileQuery) []*pb.File {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	files := make([]*pb.File, 0)
+	match := func(file *pb.File) {
+		if file != nil && !isTrashed(file) && query.matches(file) {
+			files = append(files, file)
+		}
+	}
+
+	ids, ok := store.index.candidates(query)
+	if ok {
+		for _, id := range ids {
+			match(store.data[id])
+		}
+	} else {
+		for _, file := range store.data {
+			match(file)
+		}
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		if fullPath(files[i]) != fullPath(files[j]) {
+			return fullPath(files[i]) < fullPath(files[j])
+		}
+		return files[i].GetId() < files[j].GetId()
+	})
+	return files
+}
+
+// matches reports whether the file matches every field of the query
+func (query FileQuery) matches(file *pb.File) bool {
+	if query.Owner != "" && file.GetOwner().GetName() != query.Owner {
+		return false
+	}
+	if query.Name != "" && !matchName(query.Name, file.GetTitle()) {
+		return false
+	}
+	if file.GetSize() < query.MinSize || query.MaxSize > 0 && file.GetSize() > query.MaxSize {
+		return false
+	}
+	if !query.Created.contains(file.GetCreatedAt().AsTime()) || !query.Updated.contains(file.GetUpdatedAt().AsTime()) {
+		return false
+	}
+
+	if query.ContentType != "" {
+		mediaType, wanted := mediaTypeOf(file.GetContentType()), queryMediaType(query.ContentType)
+		if mediaType != wanted && !(strings.HasSuffix(wanted, "/") && strings.HasPrefix(mediaType, wanted)) {
+			return false
+		}
+	}
+
+	for key, value := range query.Labels {
+		actual, ok := file.GetLabels()[key]
+		if !ok || value != "" && actual != value {
+			return false
+		}
+	}
+
+	if query.SharedWith != "" && findGrant(file, query.SharedWith) == nil {
+		return false
+	}
+	return true
+}
+
+// matchName matches the name of a file against a glob pattern or a substring, ignoring case
+func matchName(pattern, name string) bool {
+	pattern, name = strings.ToLower(pattern), strings.ToLower(name)
+	if !strings.ContainsAny(pattern, "*?[") {
+		return strings.Contains(name, pattern)
+	}
+
+	matched, _ := path.Match(pattern, name)
+	return matched
+}
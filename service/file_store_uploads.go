@@ -0,0 +1,108 @@
+package service
+
+import (
+	"crypto/sha256"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/Nextasy01/grpc-file-service/pb"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+func (store *InMemoryFileStore) SaveUpload(session *UploadSession) error {
+	writer, ok := session.writer.(*fileWriter)
+	if !ok || writer.store != store {
+		return errors.New("the writer of the upload wasn't created by this store")
+	}
+
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	if store.journal != nil {
+		err := store.journal.upload(session.ID, writer.file, filepath.Base(writer.temp.Name()), writer.replaces, session.Offset)
+		if err != nil {
+			return err
+		}
+	}
+
+	saved := *session
+	saved.busy = false
+	store.uploads[session.ID] = &saved
+	return nil
+}
+
+func (store *InMemoryFileStore) RemoveUpload(id string) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	if _, ok := store.uploads[id]; !ok {
+		return ErrNotFound
+	}
+
+	if store.journal != nil {
+		err := store.journal.end(id)
+		if err != nil {
+			return err
+		}
+	}
+	delete(store.uploads, id)
+	return nil
+}
+
+func (store *InMemoryFileStore) Uploads() []*UploadSession {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	uploads := make([]*UploadSession, 0, len(store.uploads))
+	for _, session := range store.uploads {
+		saved := *session
+		uploads = append(uploads, &saved)
+	}
+	return uploads
+}
+
+// reopenUploads continues the resumable uploads found in the journal, the data received after
+// the saved offset is discarded. Uploads whose data is missing are dropped
+func (store *InMemoryFileStore) reopenUploads(entries map[string]journalEntry) {
+	for id, entry := range entries {
+		writer, err := store.reopenWriter(entry)
+		if err != nil {
+			log.Printf("Cannot continue upload session %s, dropping it: %v", id, err)
+			continue
+		}
+		store.uploads[id] = &UploadSession{ID: id, File: writer.file, Offset: entry.Offset, writer: writer}
+	}
+}
+
+// reopenWriter opens the temporary file of a resumable upload to continue writing it after the saved offset
+func (store *InMemoryFileStore) reopenWriter(entry journalEntry) (*fileWriter, error) {
+	file := &pb.File{}
+	err := protojson.Unmarshal(entry.File, file)
+	if err != nil {
+		return nil, err
+	}
+
+	temp, err := os.OpenFile(filepath.Join(store.fileFolder, filepath.Base(entry.Blob)), os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+	info, err := temp.Stat()
+	if err != nil {
+		temp.Close()
+		return nil, err
+	}
+
+	writer := &fileWriter{store: store, file: file, replaces: entry.Replace, temp: temp, hash: sha256.New(), size: uint64(info.Size())}
+	if entry.Offset > writer.size {
+		err = errors.New("data of the upload is missing")
+	} else {
+		err = writer.rewind(entry.Offset)
+	}
+	if err != nil {
+		temp.Close()
+		return nil, err
+	}
+	return writer, nil
+}
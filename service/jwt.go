@@ -62,3 +62,57 @@ func (manager *JWTManager) Verify(accessToken string) (*UserClaims, error) {
 
 	return claims, nil
 }
+
+// audience of share link tokens, so they can't be used as access tokens and the other way around
+const shareLinkAudience = "share-link"
+
+// LinkClaims are the claims of a share link token, the ID is the ID of the link
+type LinkClaims struct {
+	jwt.RegisteredClaims
+	FileID string `json:"file_id"`
+	Store  string `json:"store,omitempty"` // instance of the store which created the link
+}
+
+// GenerateLink signs a token for a share link which expires with the link
+func (manager *JWTManager) GenerateLink(link *ShareLink) (string, error) {
+	claims := LinkClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        link.ID,
+			Audience:  jwt.ClaimStrings{shareLinkAudience},
+			ExpiresAt: jwt.NewNumericDate(link.ExpiresAt),
+		},
+		FileID: link.FileID,
+		Store:  link.store,
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString([]byte(manager.secretKey))
+}
+
+// VerifyLink verifies a share link token and returns its claims if it is valid and not expired
+func (manager *JWTManager) VerifyLink(linkToken string) (*LinkClaims, error) {
+	token, err := jwt.ParseWithClaims(
+		linkToken,
+		&LinkClaims{},
+		func(token *jwt.Token) (interface{}, error) {
+			_, ok := token.Method.(*jwt.SigningMethodHMAC)
+			if !ok {
+				return nil, fmt.Errorf("unexpected token signing method")
+			}
+
+			return []byte(manager.secretKey), nil
+		},
+		jwt.WithAudience(shareLinkAudience),
+	)
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid token: %w", err)
+	}
+
+	claims, ok := token.Claims.(*LinkClaims)
+	if !ok {
+		return nil, fmt.Errorf("invalid token claims")
+	}
+
+	return claims, nil
+}
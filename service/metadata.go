@@ -2,15 +2,74 @@ package service
 
 import (
 	"strconv"
+	"strings"
 
 	"github.com/Nextasy01/grpc-file-service/pb"
 	"google.golang.org/grpc/metadata"
 )
 
 func Metadata(file *pb.File) metadata.MD {
-	return metadata.New(map[string]string{
-		"ID":    file.GetId(),
-		"Title": file.GetTitle(),
-		"Size":  strconv.Itoa(int(file.GetSize())),
+	md := metadata.New(map[string]string{
+		"ID":       file.GetId(),
+		"Title":    file.GetTitle(),
+		"Size":     strconv.Itoa(int(file.GetSize())),
+		"Checksum": file.GetChecksum(),
+		"Version":  strconv.Itoa(int(file.GetVersion())),
+		// content-type is reserved by gRPC
+		"Mime-Type": file.GetContentType(),
+		"Extension": file.GetExtension(),
 	})
+
+	for key, value := range file.GetLabels() {
+		md.Set(labelHeaderPrefix+key, value)
+	}
+	if len(file.GetTags()) > 0 {
+		md.Set(tagsHeader, file.GetTags()...)
+	}
+	return md
+}
+
+// each label is sent in its own header named by the key with this prefix, all tags in the tags header
+const (
+	labelHeaderPrefix = "label-"
+	tagsHeader        = "tags"
+)
+
+// LabelsFromMetadata returns the labels and tags of a downloaded file
+func LabelsFromMetadata(md metadata.MD) (labels map[string]string, tags []string) {
+	labels = make(map[string]string)
+	for key, values := range md {
+		if strings.HasPrefix(key, labelHeaderPrefix) && len(values) > 0 {
+			labels[strings.TrimPrefix(key, labelHeaderPrefix)] = values[0]
+		}
+	}
+	return labels, md.Get(tagsHeader)
+}
+
+// streams advertise the chunk sizes of the server in these headers
+const (
+	chunkSizeHeader    = "chunk-size"
+	maxChunkSizeHeader = "max-chunk-size"
+)
+
+// ChunkSizeMetadata advertises the preferred and the maximum chunk size of the server
+func ChunkSizeMetadata(preferred, max int) metadata.MD {
+	return metadata.Pairs(
+		chunkSizeHeader, strconv.Itoa(preferred),
+		maxChunkSizeHeader, strconv.Itoa(max),
+	)
+}
+
+// ChunkSizeFromMetadata returns the chunk sizes advertised by the server, or ok = false if there are none
+func ChunkSizeFromMetadata(md metadata.MD) (preferred, max int, ok bool) {
+	preferred, err := strconv.Atoi(strings.Join(md.Get(chunkSizeHeader), ""))
+	if err != nil || preferred <= 0 {
+		return 0, 0, false
+	}
+
+	max, err = strconv.Atoi(strings.Join(md.Get(maxChunkSizeHeader), ""))
+	if err != nil || max < preferred {
+		max = preferred
+	}
+	return preferred, max, true
 }
@@ -0,0 +1,259 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/Nextasy01/grpc-file-service/pb"
+	"github.com/google/uuid"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// UploadedPart describes a part of a multipart upload received by the server
+type UploadedPart struct {
+	Number   uint32
+	Size     uint64
+	Checksum string
+}
+
+// MultipartUpload keeps the parts of a file uploaded in pieces until they're assembled
+type MultipartUpload struct {
+	ID         string
+	File       *pb.File
+	ExpiresAt  time.Time
+	dir        string
+	parts      map[uint32]UploadedPart
+	receiving  int // number of parts being received
+	completing bool
+}
+
+// Proto returns the upload as it's sent to clients
+func (upload *MultipartUpload) Proto() *pb.MultipartUpload {
+	return &pb.MultipartUpload{
+		UploadId:  upload.ID,
+		File:      upload.File,
+		ExpiresAt: timestamppb.New(upload.ExpiresAt),
+	}
+}
+
+// Part returns a received part, it's safe to call while the upload is acquired
+func (upload *MultipartUpload) Part(number uint32) (UploadedPart, bool) {
+	part, ok := upload.parts[number]
+	return part, ok
+}
+
+// partPath returns where the contents of a received part are kept
+func (upload *MultipartUpload) partPath(number uint32) string {
+	return filepath.Join(upload.dir, fmt.Sprintf("part-%05d", number))
+}
+
+type MultipartUploadStore struct {
+	mutex   sync.Mutex
+	dir     string
+	ttl     time.Duration
+	quotas  *QuotaManager // the saved parts count against the quota of the owner until the upload is removed
+	uploads map[string]*MultipartUpload
+	expiry  *expiryLoop
+}
+
+// NewMultipartUploadStore creates a store which keeps the parts in dir
+// and drops uploads unused for longer than ttl
+func NewMultipartUploadStore(dir string, quotas *QuotaManager, ttl time.Duration) *MultipartUploadStore {
+	store := &MultipartUploadStore{
+		dir:     dir,
+		ttl:     ttl,
+		quotas:  quotas,
+		uploads: make(map[string]*MultipartUpload),
+	}
+
+	interval := ttl / 2
+	if interval > time.Minute {
+		interval = time.Minute
+	}
+	store.expiry = startExpiryLoop(interval, store.RemoveExpired)
+
+	return store
+}
+
+// Close stops removing expired uploads in the background
+func (store *MultipartUploadStore) Close() {
+	store.expiry.Stop()
+}
+
+// Create starts a new multipart upload of file
+func (store *MultipartUploadStore) Create(file *pb.File) (*MultipartUpload, error) {
+	err := os.MkdirAll(store.dir, 0755)
+	if err != nil {
+		return nil, err
+	}
+
+	dir, err := os.MkdirTemp(store.dir, "multipart-*")
+	if err != nil {
+		return nil, err
+	}
+
+	id, _ := uuid.NewRandom()
+	upload := &MultipartUpload{
+		ID:        id.String(),
+		File:      file,
+		ExpiresAt: time.Now().Add(store.ttl),
+		dir:       dir,
+		parts:     make(map[uint32]UploadedPart),
+	}
+
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	store.uploads[upload.ID] = upload
+	return upload, nil
+}
+
+// Find returns a copy of the upload so its fields can be read safely
+func (store *MultipartUploadStore) Find(id string) *MultipartUpload {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	upload := store.uploads[id]
+	if upload == nil || time.Now().After(upload.ExpiresAt) {
+		return nil
+	}
+
+	found := *upload
+	found.parts = make(map[uint32]UploadedPart, len(upload.parts))
+	for number, part := range upload.parts {
+		found.parts[number] = part
+	}
+	return &found
+}
+
+// StartPart registers a part being received, parts can't be received while the upload is completed.
+// Every started part must be finished with FinishPart
+func (store *MultipartUploadStore) StartPart(id string) (*MultipartUpload, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	upload := store.uploads[id]
+	if upload == nil || time.Now().After(upload.ExpiresAt) {
+		return nil, ErrNotFound
+	}
+	if upload.completing {
+		return nil, ErrSessionBusy
+	}
+
+	upload.receiving++
+	return upload, nil
+}
+
+// SavePart moves the received contents of a part in place, replacing a previous part with the same number.
+// The size of the part has to be reserved in the quota of the owner, the upload keeps the reservation until it's removed
+func (store *MultipartUploadStore) SavePart(upload *MultipartUpload, part UploadedPart, temp string) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	err := os.Rename(temp, upload.partPath(part.Number))
+	if err != nil {
+		return err
+	}
+
+	if replaced, ok := upload.parts[part.Number]; ok {
+		store.quotas.Release(upload.File.GetOwner().GetName(), replaced.Size)
+	}
+	upload.parts[part.Number] = part
+	return nil
+}
+
+// FinishPart ends receiving a part started with StartPart and extends the lifetime of the upload
+func (store *MultipartUploadStore) FinishPart(upload *MultipartUpload) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	upload.receiving--
+	upload.ExpiresAt = time.Now().Add(store.ttl)
+}
+
+// Acquire reserves the upload for completion, it fails while parts are being received
+func (store *MultipartUploadStore) Acquire(id string) (*MultipartUpload, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	upload := store.uploads[id]
+	if upload == nil || time.Now().After(upload.ExpiresAt) {
+		return nil, ErrNotFound
+	}
+	if upload.completing || upload.receiving > 0 {
+		return nil, ErrSessionBusy
+	}
+
+	upload.completing = true
+	return upload, nil
+}
+
+// Release makes an acquired upload available again
+func (store *MultipartUploadStore) Release(upload *MultipartUpload) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	upload.completing = false
+	upload.ExpiresAt = time.Now().Add(store.ttl)
+}
+
+// Complete removes an upload acquired with Acquire once its parts are assembled
+func (store *MultipartUploadStore) Complete(upload *MultipartUpload) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	store.remove(upload)
+	return os.RemoveAll(upload.dir)
+}
+
+// Remove forgets the upload and removes its parts, it fails while parts are received or the upload is completed
+func (store *MultipartUploadStore) Remove(id string) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	upload := store.uploads[id]
+	if upload == nil {
+		return ErrNotFound
+	}
+	if upload.completing || upload.receiving > 0 {
+		return ErrSessionBusy
+	}
+
+	store.remove(upload)
+	return os.RemoveAll(upload.dir)
+}
+
+// RemoveExpired removes the uploads which weren't used in time
+func (store *MultipartUploadStore) RemoveExpired() {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	now := time.Now()
+	for id, upload := range store.uploads {
+		if upload.completing || upload.receiving > 0 || now.Before(upload.ExpiresAt) {
+			continue
+		}
+
+		log.Printf("Multipart upload %s expired, removing it", id)
+		err := os.RemoveAll(upload.dir)
+		if err != nil {
+			log.Printf("Cannot remove parts of multipart upload %s: %v", id, err)
+		}
+		store.remove(upload)
+	}
+}
+
+// remove forgets the upload and gives back the quota reserved for its saved parts, the mutex must be held
+func (store *MultipartUploadStore) remove(upload *MultipartUpload) {
+	delete(store.uploads, upload.ID)
+
+	var size uint64
+	for _, part := range upload.parts {
+		size += part.Size
+	}
+	store.quotas.Release(upload.File.GetOwner().GetName(), size)
+}
@@ -0,0 +1,123 @@
+package service
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/Nextasy01/grpc-file-service/pb"
+)
+
+// largest number of files sent on a page, larger page sizes are reduced to it
+const maxPageSize = 1000
+
+// fields files can be ordered by
+const (
+	orderByName      = "name"
+	orderBySize      = "size"
+	orderByCreatedAt = "created_at"
+	orderByUpdatedAt = "updated_at"
+)
+
+// fileOrder is the order of a listing
+type fileOrder struct {
+	field string
+	desc  bool
+}
+
+// parseOrder parses a field optionally followed by "asc" or "desc", an empty text orders by name
+func parseOrder(orderBy string) (fileOrder, error) {
+	words := strings.Fields(strings.ToLower(orderBy))
+	if len(words) == 0 {
+		return fileOrder{field: orderByName}, nil
+	}
+	if len(words) > 2 {
+		return fileOrder{}, fmt.Errorf("invalid order \"%s\", expected a field optionally followed by asc or desc", orderBy)
+	}
+
+	order := fileOrder{field: words[0]}
+	switch order.field {
+	case orderByName, orderBySize, orderByCreatedAt, orderByUpdatedAt:
+	default:
+		return fileOrder{}, fmt.Errorf("files can't be ordered by \"%s\", use name, size, created_at or updated_at", words[0])
+	}
+
+	if len(words) == 2 {
+		switch words[1] {
+		case "asc":
+		case "desc":
+			order.desc = true
+		default:
+			return fileOrder{}, fmt.Errorf("invalid direction \"%s\", use asc or desc", words[1])
+		}
+	}
+	return order, nil
+}
+
+func (order fileOrder) String() string {
+	if order.desc {
+		return order.field + " desc"
+	}
+	return order.field
+}
+
+// sortKey is the position of a file in a listing
+type sortKey struct {
+	Text   string `json:"t,omitempty"`
+	Number int64  `json:"n,omitempty"`
+	ID     string `json:"i"`
+}
+
+func (order fileOrder) key(file *pb.File) sortKey {
+	switch order.field {
+	case orderBySize:
+		return sortKey{Number: int64(file.GetSize()), ID: file.GetId()}
+	case orderByCreatedAt:
+		return sortKey{Number: file.GetCreatedAt().AsTime().UnixNano(), ID: file.GetId()}
+	case orderByUpdatedAt:
+		return sortKey{Number: file.GetUpdatedAt().AsTime().UnixNano(), ID: file.GetId()}
+	default:
+		return sortKey{Text: fullPath(file), ID: file.GetId()}
+	}
+}
+
+// less reports whether the file with key a comes before the one with key b, files with equal values are ordered by ID
+func (order fileOrder) less(a, b sortKey) bool {
+	if a.Text != b.Text {
+		return (a.Text < b.Text) != order.desc
+	}
+	if a.Number != b.Number {
+		return (a.Number < b.Number) != order.desc
+	}
+	return a.ID < b.ID
+}
+
+// pageToken tells where the next page of a listing starts. Pages start after the last file sent
+// instead of at an offset, so they stay valid while files are added or removed
+type pageToken struct {
+	Owner    string  `json:"u"`
+	Folder   string  `json:"f,omitempty"`
+	Children bool    `json:"c,omitempty"`
+	Shared   bool    `json:"s,omitempty"`
+	Order    string  `json:"o"`
+	After    sortKey `json:"a"`
+}
+
+// encode returns the token as opaque text for the client
+func (token pageToken) encode() string {
+	data, _ := json.Marshal(token)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodePageToken(text string) (pageToken, error) {
+	var token pageToken
+	data, err := base64.RawURLEncoding.DecodeString(text)
+	if err == nil {
+		err = json.Unmarshal(data, &token)
+	}
+	if err != nil {
+		return token, fmt.Errorf("invalid page token: %w", err)
+	}
+	return token, nil
+}
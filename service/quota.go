@@ -0,0 +1,118 @@
+package service
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrQuotaExceeded is returned when a user doesn't have enough storage left
+var ErrQuotaExceeded = errors.New("storage quota exceeded")
+
+// QuotaManager enforces how much storage every user may use.
+// The limit of a user is the override set for the user or else the default of the user role,
+// a limit of 0 means unlimited storage
+type QuotaManager struct {
+	mutex      sync.Mutex
+	fileStore  FileStore
+	userStore  UserStore
+	roleLimits map[string]uint64
+	userLimits map[string]uint64
+	reserved   map[string]uint64 // bytes of uploads in progress
+}
+
+func NewQuotaManager(fileStore FileStore, userStore UserStore, roleLimits map[string]uint64) *QuotaManager {
+	if roleLimits == nil {
+		roleLimits = make(map[string]uint64)
+	}
+
+	return &QuotaManager{
+		fileStore:  fileStore,
+		userStore:  userStore,
+		roleLimits: roleLimits,
+		userLimits: make(map[string]uint64),
+		reserved:   make(map[string]uint64),
+	}
+}
+
+// SetUserLimit overrides the default limit of the user role
+func (manager *QuotaManager) SetUserLimit(username string, limit uint64) {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+
+	manager.userLimits[username] = limit
+}
+
+// Limit returns the storage limit of the user in bytes
+func (manager *QuotaManager) Limit(username string) uint64 {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+
+	return manager.limit(username)
+}
+
+func (manager *QuotaManager) limit(username string) uint64 {
+	if limit, ok := manager.userLimits[username]; ok {
+		return limit
+	}
+
+	user, err := manager.userStore.Find(username)
+	if err != nil || user == nil {
+		return 0
+	}
+	return manager.roleLimits[user.Role]
+}
+
+// Check returns ErrQuotaExceeded if the user can't store size more bytes
+func (manager *QuotaManager) Check(username string, size uint64) error {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+
+	return manager.check(username, size)
+}
+
+func (manager *QuotaManager) check(username string, size uint64) error {
+	limit := manager.limit(username)
+	if limit == 0 {
+		return nil
+	}
+
+	used := manager.fileStore.Usage(username).Bytes + manager.reserved[username]
+	if used+size > limit {
+		return ErrQuotaExceeded
+	}
+	return nil
+}
+
+// Reserve sets aside size bytes for an upload in progress.
+// The reservation must be released once the upload is saved or failed
+func (manager *QuotaManager) Reserve(username string, size uint64) error {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+
+	err := manager.check(username, size)
+	if err != nil {
+		return err
+	}
+
+	manager.reserved[username] += size
+	return nil
+}
+
+// Reserved returns how many bytes are set aside for the user
+func (manager *QuotaManager) Reserved(username string) uint64 {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+
+	return manager.reserved[username]
+}
+
+// Release gives back bytes set aside with Reserve
+func (manager *QuotaManager) Release(username string, size uint64) {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+
+	manager.reserved[username] -= size
+	if manager.reserved[username] == 0 {
+		delete(manager.reserved, username)
+	}
+}
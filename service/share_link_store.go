@@ -0,0 +1,196 @@
+package service
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Nextasy01/grpc-file-service/pb"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+var (
+	// ErrLinkExhausted is returned when every download of a share link is used
+	ErrLinkExhausted = errors.New("share link has no downloads left")
+
+	// ErrWrongPassword is returned when a share link is used with the wrong password
+	ErrWrongPassword = errors.New("wrong password for the share link")
+)
+
+// ShareLink lets anyone with its token download a file without an account
+type ShareLink struct {
+	ID           string
+	FileID       string
+	Owner        string
+	CreatedAt    time.Time
+	ExpiresAt    time.Time
+	MaxDownloads uint32 // 0 means unlimited
+	Downloads    uint32
+	passwordHash []byte
+	store        string // instance of the store which created the link
+}
+
+// Proto returns the link as it's sent to clients, without its token
+func (link *ShareLink) Proto() *pb.ShareLink {
+	return &pb.ShareLink{
+		LinkId:       link.ID,
+		FileId:       link.FileID,
+		Owner:        &pb.Owner{Name: link.Owner},
+		CreatedAt:    timestamppb.New(link.CreatedAt),
+		ExpiresAt:    timestamppb.New(link.ExpiresAt),
+		MaxDownloads: link.MaxDownloads,
+		Downloads:    link.Downloads,
+		HasPassword:  len(link.passwordHash) > 0,
+	}
+}
+
+// ShareLinkStore keeps the share links until they expire or are revoked. The links are only kept in memory,
+// so they're lost when the server restarts
+type ShareLinkStore struct {
+	mutex    sync.Mutex
+	instance string
+	links    map[string]*ShareLink
+	expiry   *expiryLoop
+}
+
+func NewShareLinkStore() *ShareLinkStore {
+	instance, _ := uuid.NewRandom()
+	store := &ShareLinkStore{instance: instance.String(), links: make(map[string]*ShareLink)}
+
+	store.expiry = startExpiryLoop(time.Minute, store.RemoveExpired)
+	return store
+}
+
+// Close stops removing expired links in the background
+func (store *ShareLinkStore) Close() {
+	store.expiry.Stop()
+}
+
+// Create adds a link to the file which expires after ttl, an empty password means anyone with the token can use it
+func (store *ShareLinkStore) Create(file *pb.File, ttl time.Duration, maxDownloads uint32, password string) (*ShareLink, error) {
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	link := &ShareLink{
+		ID:           id.String(),
+		FileID:       file.GetId(),
+		Owner:        file.GetOwner().GetName(),
+		CreatedAt:    now,
+		ExpiresAt:    now.Add(ttl),
+		MaxDownloads: maxDownloads,
+		store:        store.instance,
+	}
+	if password != "" {
+		link.passwordHash, err = bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	store.links[link.ID] = link
+	found := *link
+	return &found, nil
+}
+
+// Find returns a copy of the link, nil if it doesn't exist or has expired
+func (store *ShareLinkStore) Find(id string) *ShareLink {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	link := store.links[id]
+	if link == nil || time.Now().After(link.ExpiresAt) {
+		return nil
+	}
+
+	found := *link
+	return &found
+}
+
+// Use counts a download through the link after checking its password
+func (store *ShareLinkStore) Use(id, password string) (*ShareLink, error) {
+	link := store.Find(id)
+	if link == nil {
+		return nil, ErrNotFound
+	}
+	// the password is compared without holding the lock, bcrypt is slow on purpose
+	if len(link.passwordHash) > 0 && bcrypt.CompareHashAndPassword(link.passwordHash, []byte(password)) != nil {
+		return nil, ErrWrongPassword
+	}
+
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	stored := store.links[id]
+	if stored == nil {
+		return nil, ErrNotFound
+	}
+	if stored.MaxDownloads > 0 && stored.Downloads >= stored.MaxDownloads {
+		return nil, ErrLinkExhausted
+	}
+
+	stored.Downloads++
+	found := *stored
+	return &found, nil
+}
+
+// Lost reports whether a link created by the given store instance was lost when the server restarted
+func (store *ShareLinkStore) Lost(instance string) bool {
+	return instance != store.instance
+}
+
+// List returns the links of the files of the owner ordered by creation, only of the file if fileID isn't empty
+func (store *ShareLinkStore) List(owner, fileID string) []*ShareLink {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	now := time.Now()
+	links := make([]*ShareLink, 0)
+	for _, link := range store.links {
+		if link.Owner != owner || fileID != "" && link.FileID != fileID || now.After(link.ExpiresAt) {
+			continue
+		}
+		found := *link
+		links = append(links, &found)
+	}
+
+	sort.Slice(links, func(i, j int) bool {
+		return links[i].CreatedAt.Before(links[j].CreatedAt)
+	})
+	return links
+}
+
+// Revoke removes the link, its token can't be used anymore
+func (store *ShareLinkStore) Revoke(id string) (*ShareLink, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	link := store.links[id]
+	if link == nil {
+		return nil, ErrNotFound
+	}
+
+	delete(store.links, id)
+	return link, nil
+}
+
+// RemoveExpired drops the links whose tokens have expired
+func (store *ShareLinkStore) RemoveExpired() {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	now := time.Now()
+	for id, link := range store.links {
+		if now.After(link.ExpiresAt) {
+			delete(store.links, id)
+		}
+	}
+}
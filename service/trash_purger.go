@@ -0,0 +1,37 @@
+package service
+
+import (
+	"log"
+	"time"
+)
+
+// TrashPurger permanently removes files which stayed in the trash longer than the retention period
+type TrashPurger struct {
+	fileStore FileStore
+	retention time.Duration
+}
+
+func NewTrashPurger(fileStore FileStore, retention time.Duration) *TrashPurger {
+	return &TrashPurger{fileStore: fileStore, retention: retention}
+}
+
+// Purge removes the expired files and returns how many were removed
+func (purger *TrashPurger) Purge() (int, error) {
+	files, err := purger.fileStore.PurgeTrash(time.Now().Add(-purger.retention))
+	for _, file := range files {
+		log.Printf("Purged file %s of %s from the trash", file.GetTitle(), file.GetOwner().GetName())
+	}
+	return len(files), err
+}
+
+// Start purges the trash every interval in the background
+func (purger *TrashPurger) Start(interval time.Duration) {
+	go func() {
+		for range time.Tick(interval) {
+			_, err := purger.Purge()
+			if err != nil {
+				log.Println("Cannot purge the trash ", err)
+			}
+		}
+	}()
+}
@@ -0,0 +1,187 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/Nextasy01/grpc-file-service/pb"
+	"github.com/google/uuid"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// ErrSessionBusy is returned when an upload session is already used by another stream
+var ErrSessionBusy = errors.New("upload session is in use")
+
+// UploadSession keeps the state of an upload which can be resumed after a failure
+type UploadSession struct {
+	ID        string
+	File      *pb.File
+	Offset    uint64 // number of bytes durably written
+	ExpiresAt time.Time
+	writer    FileWriter
+	busy      bool
+}
+
+// Proto returns the session as it's sent to clients
+func (session *UploadSession) Proto() *pb.UploadSession {
+	return &pb.UploadSession{
+		SessionId: session.ID,
+		File:      session.File,
+		Offset:    session.Offset,
+		ExpiresAt: timestamppb.New(session.ExpiresAt),
+	}
+}
+
+type UploadSessionStore struct {
+	mutex    sync.Mutex
+	ttl      time.Duration
+	files    FileStore // saves the offsets of the sessions so they survive a restart
+	sessions map[string]*UploadSession
+	expiry   *expiryLoop
+}
+
+// NewUploadSessionStore creates a store which drops sessions unused for longer than ttl.
+// The sessions saved in fileStore before a restart are continued
+func NewUploadSessionStore(fileStore FileStore, ttl time.Duration) *UploadSessionStore {
+	store := &UploadSessionStore{
+		ttl:      ttl,
+		files:    fileStore,
+		sessions: make(map[string]*UploadSession),
+	}
+	// the server may have been down for longer than ttl, so the saved sessions get a full lifetime again
+	for _, session := range fileStore.Uploads() {
+		session.ExpiresAt = time.Now().Add(ttl)
+		store.sessions[session.ID] = session
+	}
+
+	interval := ttl / 2
+	if interval > time.Minute {
+		interval = time.Minute
+	}
+	store.expiry = startExpiryLoop(interval, store.RemoveExpired)
+
+	return store
+}
+
+// Close stops removing expired sessions in the background
+func (store *UploadSessionStore) Close() {
+	store.expiry.Stop()
+}
+
+// Create starts a new session writing the contents of file to writer, which has to be created by the file store
+func (store *UploadSessionStore) Create(file *pb.File, writer FileWriter) (*UploadSession, error) {
+	id, _ := uuid.NewRandom()
+	session := &UploadSession{
+		ID:        id.String(),
+		File:      file,
+		ExpiresAt: time.Now().Add(store.ttl),
+		writer:    writer,
+	}
+
+	err := store.files.SaveUpload(session)
+	if err != nil {
+		return nil, err
+	}
+
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	store.sessions[session.ID] = session
+	return session, nil
+}
+
+// Find returns a copy of the session so its fields can be read safely
+func (store *UploadSessionStore) Find(id string) *UploadSession {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	session := store.sessions[id]
+	if session == nil || time.Now().After(session.ExpiresAt) {
+		return nil
+	}
+
+	found := *session
+	return &found
+}
+
+// Acquire reserves the session for a single stream until it's released
+func (store *UploadSessionStore) Acquire(id string) (*UploadSession, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	session := store.sessions[id]
+	if session == nil || time.Now().After(session.ExpiresAt) {
+		return nil, ErrNotFound
+	}
+	if session.busy {
+		return nil, ErrSessionBusy
+	}
+
+	session.busy = true
+	return session, nil
+}
+
+// SetOffset records how many bytes of the session are durably written, the session has to be acquired
+func (store *UploadSessionStore) SetOffset(session *UploadSession, offset uint64) error {
+	saved := *session
+	saved.Offset = offset
+	err := store.files.SaveUpload(&saved)
+	if err != nil {
+		return err
+	}
+
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	session.Offset = offset
+	return nil
+}
+
+// Release makes the session available again and extends its lifetime
+func (store *UploadSessionStore) Release(session *UploadSession) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	session.busy = false
+	session.ExpiresAt = time.Now().Add(store.ttl)
+}
+
+// Remove forgets the session, its writer has to be committed or aborted by the caller
+func (store *UploadSessionStore) Remove(id string) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	delete(store.sessions, id)
+	store.forget(id)
+}
+
+// RemoveExpired aborts the sessions which weren't used in time
+func (store *UploadSessionStore) RemoveExpired() {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	now := time.Now()
+	for id, session := range store.sessions {
+		if session.busy || now.Before(session.ExpiresAt) {
+			continue
+		}
+
+		log.Printf("Upload session %s expired, removing it", id)
+		err := session.writer.Abort()
+		if err != nil {
+			log.Printf("Cannot remove data of upload session %s: %v", id, err)
+		}
+		delete(store.sessions, id)
+		store.forget(id)
+	}
+}
+
+// forget removes the saved offset of a session which was finished or abandoned
+func (store *UploadSessionStore) forget(id string) {
+	err := store.files.RemoveUpload(id)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		log.Printf("Cannot remove upload session %s from the file store: %v", id, err)
+	}
+}
@@ -7,6 +7,8 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
+const adminRole = "admin"
+
 type User struct {
 	Id             string
 	Username       string
@@ -0,0 +1,130 @@
+package service_test
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Nextasy01/grpc-file-service/pb"
+	"github.com/Nextasy01/grpc-file-service/service"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestDownloadArchive(t *testing.T) {
+	t.Parallel()
+
+	fileStore := service.NewInMemoryFileStore(t.TempDir())
+	jwtManager := service.NewJWTManager("test-secret", time.Minute)
+	fileClient := newTestFileClient(t, startTestAuthFileServer(t, fileStore, jwtManager))
+
+	owner := createUser(t, service.NewInMemoryUserStore(), "owner", "secret", "user")
+	other := createUser(t, service.NewInMemoryUserStore(), "other", "secret", "user")
+	ctx := authContext(t, jwtManager, owner)
+
+	report := &pb.File{Title: "report.txt", Owner: &pb.Owner{Name: owner.Username}}
+	require.NoError(t, fileStore.Save(report, strings.NewReader("report")))
+	data := &pb.File{Title: "data.csv", Owner: &pb.Owner{Name: owner.Username}}
+	require.NoError(t, fileStore.Save(data, strings.NewReader("a,b\n1,2\n")))
+	sameTitle := &pb.File{Title: "data.csv", Owner: &pb.Owner{Name: owner.Username}}
+	require.NoError(t, fileStore.Save(sameTitle, strings.NewReader("a,b\n1,2\n")))
+	foreign := &pb.File{Title: "secret.txt", Owner: &pb.Owner{Name: other.Username}}
+	require.NoError(t, fileStore.Save(foreign, strings.NewReader("secret")))
+
+	archive, err := downloadArchive(ctx, fileClient, &pb.DownloadArchiveRequest{
+		FileIds: []string{report.GetId(), data.GetId()},
+		Format:  pb.ArchiveFormat_ZIP,
+	})
+	require.NoError(t, err)
+
+	zipReader, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	require.NoError(t, err)
+	entries := make(map[string]string)
+	for _, entry := range zipReader.File {
+		contents, err := entry.Open()
+		require.NoError(t, err)
+		entries[entry.Name] = readAll(t, contents)
+	}
+	require.Equal(t, map[string]string{"report.txt": "report", "data.csv": "a,b\n1,2\n"}, entries)
+
+	// all files of the user, files with the same title are numbered
+	archive, err = downloadArchive(ctx, fileClient, &pb.DownloadArchiveRequest{
+		Owner:  &pb.Owner{Name: owner.Username},
+		Format: pb.ArchiveFormat_TAR_GZ,
+	})
+	require.NoError(t, err)
+
+	gzipReader, err := gzip.NewReader(bytes.NewReader(archive))
+	require.NoError(t, err)
+	tarReader := tar.NewReader(gzipReader)
+	entries = make(map[string]string)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		entries[header.Name] = readAll(t, tarReader)
+	}
+	require.Equal(t, map[string]string{
+		"data (2).csv": "a,b\n1,2\n",
+		"data.csv":     "a,b\n1,2\n",
+		"report.txt":   "report",
+	}, entries)
+
+	// only the files of the user in the folder with all of the tags
+	tagged := &pb.File{Title: "q1.txt", Path: "docs/reports", Tags: []string{"final", "q1"}, Owner: &pb.Owner{Name: owner.Username}}
+	require.NoError(t, fileStore.Save(tagged, strings.NewReader("q1")))
+	draft := &pb.File{Title: "q2.txt", Path: "docs", Tags: []string{"q2"}, Owner: &pb.Owner{Name: owner.Username}}
+	require.NoError(t, fileStore.Save(draft, strings.NewReader("q2")))
+	outside := &pb.File{Title: "q3.txt", Tags: []string{"final"}, Owner: &pb.Owner{Name: owner.Username}}
+	require.NoError(t, fileStore.Save(outside, strings.NewReader("q3")))
+
+	archive, err = downloadArchive(ctx, fileClient, &pb.DownloadArchiveRequest{
+		Owner:  &pb.Owner{Name: owner.Username},
+		Folder: "docs",
+		Tags:   []string{"final"},
+		Format: pb.ArchiveFormat_TAR_GZ,
+	})
+	require.NoError(t, err)
+	gzipReader, err = gzip.NewReader(bytes.NewReader(archive))
+	require.NoError(t, err)
+	tarReader = tar.NewReader(gzipReader)
+	header, err := tarReader.Next()
+	require.NoError(t, err)
+	require.Equal(t, "docs/reports/q1.txt", header.Name)
+	require.Equal(t, "q1", readAll(t, tarReader))
+	_, err = tarReader.Next()
+	require.Equal(t, io.EOF, err)
+
+	_, err = downloadArchive(ctx, fileClient, &pb.DownloadArchiveRequest{Owner: &pb.Owner{Name: owner.Username}, Tags: []string{"missing"}})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = downloadArchive(ctx, fileClient, &pb.DownloadArchiveRequest{Owner: &pb.Owner{Name: owner.Username}, Folder: "../other"})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = downloadArchive(ctx, fileClient, &pb.DownloadArchiveRequest{FileIds: []string{report.GetId(), foreign.GetId()}})
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
+func downloadArchive(ctx context.Context, fileClient pb.FileServiceClient, req *pb.DownloadArchiveRequest) ([]byte, error) {
+	stream, err := fileClient.DownloadArchive(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := receiveDownload(stream)
+	return []byte(data), err
+}
+
+func readAll(t *testing.T, r io.Reader) string {
+	data, err := io.ReadAll(r)
+	require.NoError(t, err)
+	return string(data)
+}
@@ -0,0 +1,133 @@
+package service_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/rand"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Nextasy01/grpc-file-service/pb"
+	"github.com/Nextasy01/grpc-file-service/service"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestChunkSizes(t *testing.T) {
+	t.Parallel()
+
+	fileStore := service.NewInMemoryFileStore(t.TempDir())
+	fileServer := newTestFileServer(t, fileStore)
+	require.Error(t, fileServer.SetChunkSizes(8, 4))
+	require.NoError(t, fileServer.SetChunkSizes(4, 8))
+	jwtManager := service.NewJWTManager("test-secret", time.Minute)
+	fileClient := newTestFileClient(t, serveTestFileServer(t, fileServer, testAuthServerOptions(jwtManager)...))
+	ctx := authContext(t, jwtManager, createUser(t, service.NewInMemoryUserStore(), "testUser", "secret", "user"))
+
+	stream, err := fileClient.Upload(ctx)
+	require.NoError(t, err)
+	require.NoError(t, stream.Send(&pb.UploadFileRequest{File: &pb.File{Title: "big.txt", Owner: &pb.Owner{Name: "testUser"}}}))
+
+	md, err := stream.Header()
+	require.NoError(t, err)
+	preferred, max, ok := service.ChunkSizeFromMetadata(md)
+	require.True(t, ok)
+	require.Equal(t, 4, preferred)
+	require.Equal(t, 8, max)
+
+	require.NoError(t, stream.Send(&pb.UploadFileRequest{Chunk: []byte("123456789")}))
+	_, err = stream.CloseAndRecv()
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	file := &pb.File{Title: "digits.txt", Owner: &pb.Owner{Name: "testUser"}}
+	require.NoError(t, fileStore.Save(file, strings.NewReader("0123456789")))
+
+	testCases := []struct {
+		name      string
+		chunkSize uint32
+		chunks    []string
+	}{
+		{name: "preferred by the server", chunks: []string{"0123", "4567", "89"}},
+		{name: "requested by the client", chunkSize: 3, chunks: []string{"012", "345", "678", "9"}},
+		{name: "capped by the maximum", chunkSize: 100, chunks: []string{"01234567", "89"}},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			stream, err := fileClient.Download(ctx, &pb.DownloadFileRequest{FileId: file.GetId(), ChunkSize: tc.chunkSize})
+			require.NoError(t, err)
+
+			var chunks []string
+			for {
+				res, err := stream.Recv()
+				if err == io.EOF {
+					break
+				}
+				require.NoError(t, err)
+				chunks = append(chunks, string(res.GetChunk()))
+			}
+			require.Equal(t, tc.chunks, chunks)
+		})
+	}
+}
+
+func TestLargeChunkDownloads(t *testing.T) {
+	t.Parallel()
+
+	fileStore := service.NewInMemoryFileStore(t.TempDir())
+	fileServer := newTestFileServer(t, fileStore)
+	// chunks above the 4MB gRPC receives by default
+	require.NoError(t, fileServer.SetChunkSizes(5<<20, 6<<20))
+	jwtManager := service.NewJWTManager("test-secret", time.Minute)
+	address := serveTestFileServer(t, fileServer, append(testAuthServerOptions(jwtManager), grpc.MaxRecvMsgSize(6<<20+service.MessageOverhead))...)
+	md, _ := metadata.FromOutgoingContext(authContext(t, jwtManager, createUser(t, service.NewInMemoryUserStore(), "testUser", "secret", "user")))
+
+	conn, err := grpc.Dial(address, grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithStreamInterceptor(func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+			return streamer(metadata.NewOutgoingContext(ctx, md), desc, cc, method, opts...)
+		}))
+	require.NoError(t, err)
+	defer conn.Close()
+	fileClient := service.NewFileClient(conn)
+
+	// random data doesn't shrink in the archive
+	random := make([]byte, 6<<20)
+	_, err = rand.Read(random)
+	require.NoError(t, err)
+	data := string(random)
+	file := &pb.File{Title: "large.txt", Owner: &pb.Owner{Name: "testUser"}}
+	require.NoError(t, fileStore.Save(file, strings.NewReader(data)))
+
+	download := func() {
+		var archive bytes.Buffer
+		require.NoError(t, fileClient.DownloadArchive([]string{file.GetId()}, nil, "", nil, pb.ArchiveFormat_TAR_GZ, &archive))
+		gzipReader, err := gzip.NewReader(&archive)
+		require.NoError(t, err)
+		tarReader := tar.NewReader(gzipReader)
+		_, err = tarReader.Next()
+		require.NoError(t, err)
+		require.Equal(t, data, readAll(t, tarReader))
+	}
+
+	// before the client learned the maximum chunk size of the server
+	download()
+
+	// uploads tell the client the maximum chunk size
+	root := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(root, "small.txt"), []byte("small"), 0644))
+	summary, err := fileClient.UploadDirectory(&pb.Owner{Name: "testUser"}, root, 1)
+	require.NoError(t, err)
+	require.Equal(t, 1, summary.Uploaded)
+	download()
+}
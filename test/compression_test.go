@@ -0,0 +1,61 @@
+package service_test
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Nextasy01/grpc-file-service/pb"
+	"github.com/Nextasy01/grpc-file-service/service"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+func TestCompression(t *testing.T) {
+	t.Parallel()
+
+	fileStore := service.NewInMemoryFileStore(t.TempDir())
+	jwtManager := service.NewJWTManager("test-secret", time.Minute)
+	fileClient := newTestFileClient(t, startTestAuthFileServer(t, fileStore, jwtManager))
+	ctx := authContext(t, jwtManager, createUser(t, service.NewInMemoryUserStore(), "testUser", "secret", "user"))
+	data := strings.Repeat("timestamp,level,message\n2023-07-01,info,started\n", 500)
+
+	for _, compression := range []string{service.Gzip, service.Zstd} {
+		compression := compression
+		t.Run(compression, func(t *testing.T) {
+			stream, err := fileClient.Upload(ctx, grpc.UseCompressor(compression))
+			require.NoError(t, err)
+
+			owner := &pb.Owner{Name: "testUser"}
+			require.NoError(t, stream.Send(&pb.UploadFileRequest{File: &pb.File{Title: compression + ".csv", Owner: owner}}))
+			for rest := data; len(rest) > 0; {
+				n := 4096
+				if len(rest) < n {
+					n = len(rest)
+				}
+				require.NoError(t, stream.Send(&pb.UploadFileRequest{Chunk: []byte(rest[:n])}))
+				rest = rest[n:]
+			}
+			res, err := stream.CloseAndRecv()
+			require.NoError(t, err)
+
+			// the stored file and its size aren't compressed
+			require.EqualValues(t, len(data), res.GetSize())
+			require.EqualValues(t, len(data), res.GetFile().GetSize())
+			contents, err := fileStore.Open(res.GetFile().GetId())
+			require.NoError(t, err)
+			defer contents.Close()
+			stored, err := io.ReadAll(contents)
+			require.NoError(t, err)
+			require.Equal(t, data, string(stored))
+
+			download, err := fileClient.Download(ctx,
+				&pb.DownloadFileRequest{FileId: res.GetFile().GetId()}, grpc.UseCompressor(compression))
+			require.NoError(t, err)
+			downloaded, err := receiveDownload(download)
+			require.NoError(t, err)
+			require.Equal(t, data, downloaded)
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package service_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Nextasy01/grpc-file-service/pb"
+	"github.com/Nextasy01/grpc-file-service/service"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContentType(t *testing.T) {
+	t.Parallel()
+
+	fileStore := service.NewInMemoryFileStore(t.TempDir())
+	jwtManager := service.NewJWTManager("test-secret", time.Minute)
+	fileClient := newTestFileClient(t, startTestAuthFileServer(t, fileStore, jwtManager))
+	ctx := authContext(t, jwtManager, createUser(t, service.NewInMemoryUserStore(), "testUser", "secret", "user"))
+	owner := &pb.Owner{Name: "testUser"}
+
+	testCases := []struct {
+		title       string
+		data        string
+		contentType string
+		extension   string
+	}{
+		{title: "picture", data: "\x89PNG\x0D\x0A\x1A\x0A\x00\x00\x00\x0DIHDR", contentType: "image/png"},
+		{title: "page.txt", data: "<!DOCTYPE html><html></html>", contentType: "text/html; charset=utf-8", extension: ".txt"},
+		{title: "config.json", data: `{"key": "value"}`, contentType: "application/json", extension: ".json"},
+		{title: ".bashrc", data: "export PATH=$PATH:~/bin\n", contentType: "text/plain; charset=utf-8"},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			res, err := uploadFile(ctx, fileClient, owner, tc.title, tc.data)
+			require.NoError(t, err)
+			require.Equal(t, tc.contentType, res.GetFile().GetContentType())
+			require.Equal(t, tc.extension, res.GetFile().GetExtension())
+
+			stream, err := fileClient.Download(ctx, &pb.DownloadFileRequest{FileId: res.GetFile().GetId()})
+			require.NoError(t, err)
+			md, err := stream.Header()
+			require.NoError(t, err)
+			require.Equal(t, []string{tc.contentType}, md.Get("mime-type"))
+
+			data, err := receiveDownload(stream)
+			require.NoError(t, err)
+			require.Equal(t, tc.data, data)
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package service_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Nextasy01/grpc-file-service/pb"
+	"github.com/Nextasy01/grpc-file-service/service"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestCopy(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	fileStore, err := service.NewDiskFileStore(dir)
+	require.NoError(t, err)
+
+	jwtManager := service.NewJWTManager("test-secret", time.Minute)
+	userStore := service.NewInMemoryUserStore()
+	owner := createUser(t, userStore, "alice", "secret", "user")
+	other := createUser(t, userStore, "bob", "secret", "user")
+	ownerCtx := authContext(t, jwtManager, owner)
+	otherCtx := authContext(t, jwtManager, other)
+
+	quotas := service.NewQuotaManager(fileStore, userStore, map[string]uint64{"user": 30})
+	fileServer := service.NewFileServer(fileStore, service.NewUploadSessionStore(fileStore, time.Hour), service.NewMultipartUploadStore(t.TempDir(), quotas, time.Hour), quotas)
+	t.Cleanup(fileServer.Close)
+	fileClient := newTestFileClient(t, serveTestFileServer(t, fileServer, testAuthServerOptions(jwtManager)...))
+
+	original := &pb.File{Title: "report.txt", Path: "docs", Owner: &pb.Owner{Name: owner.Username}, Labels: map[string]string{"year": "2023"}}
+	require.NoError(t, fileStore.Save(original, strings.NewReader("0123456789")))
+
+	copyFile := func(ctx context.Context, req *pb.CopyRequest) (*pb.File, error) {
+		req.FileId = original.GetId()
+		res, err := fileClient.Copy(ctx, req)
+		return res.GetFile(), err
+	}
+	download := func(ctx context.Context, id string) string {
+		stream, err := fileClient.Download(ctx, &pb.DownloadFileRequest{FileId: id})
+		require.NoError(t, err)
+		data, err := receiveDownload(stream)
+		require.NoError(t, err)
+		return data
+	}
+
+	_, err = copyFile(otherCtx, &pb.CopyRequest{})
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	// the copy keeps the folder and labels of the original and shares its contents
+	backup, err := copyFile(ownerCtx, &pb.CopyRequest{Title: "backup.txt"})
+	require.NoError(t, err)
+	require.NotEqual(t, original.GetId(), backup.GetId())
+	require.Equal(t, "docs", backup.GetPath())
+	require.Equal(t, original.GetChecksum(), backup.GetChecksum())
+	require.Equal(t, map[string]string{"year": "2023"}, backup.GetLabels())
+	require.Len(t, blobFiles(t, dir), 1)
+	require.Equal(t, "0123456789", download(ownerCtx, backup.GetId()))
+
+	_, err = copyFile(ownerCtx, &pb.CopyRequest{Title: "backup.txt"})
+	require.Equal(t, codes.AlreadyExists, status.Code(err))
+	_, err = copyFile(ownerCtx, &pb.CopyRequest{Destination: "../outside"})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = copyFile(ownerCtx, &pb.CopyRequest{Owner: &pb.Owner{Name: other.Username}})
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	// copies count towards the quota even though the contents are stored once,
+	// only one of the copies made at the same time fits
+	results := make([]error, 5)
+	var wg sync.WaitGroup
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, results[i] = copyFile(ownerCtx, &pb.CopyRequest{Destination: ".", Title: fmt.Sprintf("top%d.txt", i)})
+		}(i)
+	}
+	wg.Wait()
+	copied := 0
+	for _, err := range results {
+		if err == nil {
+			copied++
+			continue
+		}
+		require.Equal(t, codes.ResourceExhausted, status.Code(err))
+	}
+	require.Equal(t, 1, copied)
+	require.EqualValues(t, 30, fileStore.Usage(owner.Username).Bytes)
+
+	// users the file is shared with can copy it into their own folders
+	_, err = fileClient.ShareFile(ownerCtx, &pb.ShareFileRequest{FileId: original.GetId(), User: &pb.Owner{Name: other.Username}, Permission: pb.Permission_READ})
+	require.NoError(t, err)
+	mine, err := copyFile(otherCtx, &pb.CopyRequest{Destination: "inbox"})
+	require.NoError(t, err)
+	require.Equal(t, other.Username, mine.GetOwner().GetName())
+	require.Equal(t, "inbox/report.txt", mine.GetPath()+"/"+mine.GetTitle())
+	require.Empty(t, mine.GetGrants())
+
+	// the contents stay as long as a copy uses them
+	_, err = fileClient.Delete(ownerCtx, &pb.DeleteFileRequest{FileId: original.GetId(), Permanent: true})
+	require.NoError(t, err)
+	require.Equal(t, "0123456789", download(otherCtx, mine.GetId()))
+	require.Len(t, blobFiles(t, dir), 1)
+
+	reloaded, err := service.NewDiskFileStore(dir)
+	require.NoError(t, err)
+	contents, err := reloaded.Open(mine.GetId())
+	require.NoError(t, err)
+	defer contents.Close()
+	data, err := io.ReadAll(contents)
+	require.NoError(t, err)
+	require.Equal(t, "0123456789", string(data))
+}
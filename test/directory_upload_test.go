@@ -0,0 +1,63 @@
+package service_test
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/Nextasy01/grpc-file-service/pb"
+	"github.com/Nextasy01/grpc-file-service/service"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+func TestUploadDirectory(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	for name, data := range map[string]string{
+		"readme.md":              "# project",
+		"src/main.go":            "package main",
+		"src/internal/util.go":   "package internal",
+		"results/2023/07/01.csv": "a,b\n",
+	} {
+		name := filepath.Join(root, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(name), 0755))
+		require.NoError(t, os.WriteFile(name, []byte(data), 0644))
+	}
+
+	fileStore := service.NewInMemoryFileStore(t.TempDir())
+	conn, err := grpc.Dial(startTestFileServer(t, fileStore), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	summary, err := service.NewFileClient(conn).UploadDirectory(&pb.Owner{Name: "testUser"}, root, 2)
+	require.NoError(t, err)
+	require.Equal(t, 4, summary.Uploaded)
+	require.Empty(t, summary.Failed)
+
+	var paths []string
+	for _, file := range fileStore.List("testUser") {
+		paths = append(paths, path.Join(file.GetPath(), file.GetTitle()))
+	}
+	sort.Strings(paths)
+	require.Equal(t, []string{"readme.md", "results/2023/07/01.csv", "src/internal/util.go", "src/main.go"}, paths)
+}
+
+func TestInvalidPath(t *testing.T) {
+	t.Parallel()
+
+	fileStore := service.NewInMemoryFileStore(t.TempDir())
+	for _, dir := range []string{"../outside", "/absolute", "a/../../b"} {
+		err := fileStore.Save(&pb.File{Title: "x.txt", Path: dir}, strings.NewReader("x"))
+		require.ErrorIs(t, err, service.ErrInvalidPath, dir)
+	}
+
+	file := &pb.File{Title: "x.txt", Path: "a/./b/../c/"}
+	require.NoError(t, fileStore.Save(file, strings.NewReader("x")))
+	require.Equal(t, "a/c", file.GetPath())
+}
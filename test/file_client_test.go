@@ -2,21 +2,24 @@ package service_test
 
 import (
 	"bufio"
-	"context"
 	"fmt"
 	"io"
 	"log"
 	"net"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/Nextasy01/grpc-file-service/pb"
 	"github.com/Nextasy01/grpc-file-service/service"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
@@ -25,19 +28,22 @@ func TestEverything(t *testing.T) {
 
 	uploadFolder := "../files"
 	downloadFolder := "../temp_files"
+	require.NoError(t, os.MkdirAll(downloadFolder, os.ModePerm))
 
 	fileStore := service.NewInMemoryFileStore(uploadFolder)
 	userStore := service.NewInMemoryUserStore()
 	user := createUser(t, userStore, "testUser", "secret", "admin")
-	serverAddress := startTestFileServer(t, fileStore)
+	jwtManager := service.NewJWTManager("test-secret", time.Minute)
+	serverAddress := startTestAuthFileServer(t, fileStore, jwtManager)
 	fileClient := newTestFileClient(t, serverAddress)
+	ctx := authContext(t, jwtManager, user)
 
 	filePath := "../moon.jpg"
 	file, err := os.Open(filePath)
 	require.NoError(t, err)
 	defer file.Close()
 
-	stream, err := fileClient.Upload(context.Background())
+	stream, err := fileClient.Upload(ctx)
 	require.NoError(t, err)
 
 	newId, err := uuid.NewRandom()
@@ -77,6 +83,8 @@ func TestEverything(t *testing.T) {
 	require.FileExists(t, savedFilePath) // check if file is saved
 
 	fileId := res.GetFile().GetId() // saving to download by id later
+	checksum := res.GetFile().GetChecksum()
+	require.Len(t, checksum, 64) // hex encoded SHA-256
 
 	// Since we don't use persistent storage, we are testing list call along with upload at the same time
 	t.Run("List Files", func(t *testing.T) {
@@ -88,7 +96,7 @@ func TestEverything(t *testing.T) {
 			Name:     user.Username,
 			Password: user.HashedPassword,
 		}}
-		stream, err := fileClient.List(context.Background(), req)
+		stream, err := fileClient.List(ctx, req)
 		require.NoError(t, err)
 
 		log.Println("Your files:")
@@ -111,12 +119,13 @@ func TestEverything(t *testing.T) {
 
 		req := &pb.DownloadFileRequest{FileId: fileId}
 
-		stream, err := fileClient.Download(context.Background(), req)
+		stream, err := fileClient.Download(ctx, req)
 		require.NoError(t, err)
 
 		md, err := stream.Header()
 		require.NoError(t, err)
 		require.Equal(t, fileId, md.Get("id")[0]) //check if we are getting the correct file
+		require.Equal(t, checksum, md.Get("checksum")[0])
 
 		r, w := io.Pipe()
 		// defer require.NoError(t, r.Close())
@@ -140,18 +149,46 @@ func TestEverything(t *testing.T) {
 
 	})
 
+	// The server must refuse to save a file which doesn't match the checksum sent by the client
+	t.Run("Upload With Wrong Checksum", func(t *testing.T) {
+		stream, err := fileClient.Upload(ctx)
+		require.NoError(t, err)
+
+		require.NoError(t, stream.Send(&pb.UploadFileRequest{File: &pb.File{
+			Title:    "broken.txt",
+			Owner:    &pb.Owner{Name: user.Username},
+			Checksum: strings.Repeat("0", 64),
+		}}))
+		require.NoError(t, stream.Send(&pb.UploadFileRequest{Chunk: []byte("some data")}))
+
+		_, err = stream.CloseAndRecv()
+		require.Equal(t, codes.DataLoss, status.Code(err))
+	})
+
 }
 
 func startTestFileServer(t *testing.T, fileStore service.FileStore) string {
-	laptopServer := service.NewFileServer(fileStore)
+	return serveTestFileServer(t, newTestFileServer(t, fileStore))
+}
+
+// newTestFileServer creates a file server without storage limits
+func newTestFileServer(t *testing.T, fileStore service.FileStore) *service.FileServer {
+	quotas := service.NewQuotaManager(fileStore, service.NewInMemoryUserStore(), nil)
+	multipartUploads := service.NewMultipartUploadStore(t.TempDir(), quotas, time.Hour)
+	fileServer := service.NewFileServer(fileStore, service.NewUploadSessionStore(fileStore, time.Hour), multipartUploads, quotas)
+	t.Cleanup(fileServer.Close)
+	return fileServer
+}
 
-	grpcServer := grpc.NewServer()
-	pb.RegisterFileServiceServer(grpcServer, laptopServer)
+func serveTestFileServer(t *testing.T, fileServer *service.FileServer, opts ...grpc.ServerOption) string {
+	grpcServer := grpc.NewServer(opts...)
+	pb.RegisterFileServiceServer(grpcServer, fileServer)
 
 	listener, err := net.Listen("tcp", ":0") // random available port
 	require.NoError(t, err)
 
 	go grpcServer.Serve(listener)
+	t.Cleanup(grpcServer.Stop)
 
 	return listener.Addr().String()
 }
@@ -0,0 +1,74 @@
+package service_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Nextasy01/grpc-file-service/pb"
+	"github.com/Nextasy01/grpc-file-service/service"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const testFileServicePath = "/file.service.FileService/"
+
+func TestDeleteFile(t *testing.T) {
+	t.Parallel()
+
+	fileStore := service.NewInMemoryFileStore(t.TempDir())
+	jwtManager := service.NewJWTManager("test-secret", time.Minute)
+	serverAddress := startTestAuthFileServer(t, fileStore, jwtManager)
+	fileClient := newTestFileClient(t, serverAddress)
+
+	owner := createUser(t, service.NewInMemoryUserStore(), "owner", "secret", "user")
+	other := createUser(t, service.NewInMemoryUserStore(), "other", "secret", "user")
+
+	file := &pb.File{Title: "report.txt", Owner: &pb.Owner{Name: owner.Username}}
+	require.NoError(t, fileStore.Save(file, strings.NewReader("report")))
+
+	_, err := fileClient.Delete(authContext(t, jwtManager, other), &pb.DeleteFileRequest{FileId: file.GetId()})
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	res, err := fileClient.Delete(authContext(t, jwtManager, owner), &pb.DeleteFileRequest{FileId: file.GetId()})
+	require.NoError(t, err)
+	require.Equal(t, file.GetId(), res.GetFile().GetId())
+	require.Nil(t, fileStore.Find(file.GetId()))
+
+	_, err = fileClient.Delete(authContext(t, jwtManager, owner), &pb.DeleteFileRequest{FileId: file.GetId()})
+	require.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func startTestAuthFileServer(t *testing.T, fileStore service.FileStore, jwtManager *service.JWTManager) string {
+	return serveTestFileServer(t, newTestFileServer(t, fileStore), testAuthServerOptions(jwtManager)...)
+}
+
+// testAuthServerOptions make every file service RPC require authentication
+func testAuthServerOptions(jwtManager *service.JWTManager) []grpc.ServerOption {
+	roles := []string{"admin", "user"}
+	accessibleRoles := make(map[string][]string)
+	for _, method := range pb.FileService_ServiceDesc.Methods {
+		accessibleRoles[testFileServicePath+method.MethodName] = roles
+	}
+	for _, stream := range pb.FileService_ServiceDesc.Streams {
+		accessibleRoles[testFileServicePath+stream.StreamName] = roles
+	}
+	// as on the server, share links are their own authorization
+	delete(accessibleRoles, testFileServicePath+"DownloadShared")
+	interceptor := service.NewAuthInterceptor(jwtManager, accessibleRoles)
+
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(interceptor.Unary()),
+		grpc.ChainStreamInterceptor(interceptor.Stream()),
+	}
+}
+
+func authContext(t *testing.T, jwtManager *service.JWTManager, user *service.User) context.Context {
+	token, err := jwtManager.Generate(user)
+	require.NoError(t, err)
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", token)
+}
@@ -0,0 +1,71 @@
+package service_test
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Nextasy01/grpc-file-service/pb"
+	"github.com/Nextasy01/grpc-file-service/service"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestDownloadRange(t *testing.T) {
+	t.Parallel()
+
+	fileStore := service.NewInMemoryFileStore(t.TempDir())
+	jwtManager := service.NewJWTManager("test-secret", time.Minute)
+	serverAddress := startTestAuthFileServer(t, fileStore, jwtManager)
+	fileClient := newTestFileClient(t, serverAddress)
+	ctx := authContext(t, jwtManager, createUser(t, service.NewInMemoryUserStore(), "testUser", "secret", "user"))
+
+	file := &pb.File{Title: "digits.txt", Owner: &pb.Owner{Name: "testUser"}}
+	require.NoError(t, fileStore.Save(file, strings.NewReader("0123456789")))
+
+	testCases := []struct {
+		name   string
+		offset uint64
+		length uint64
+		data   string
+		code   codes.Code
+	}{
+		{name: "whole file", data: "0123456789"},
+		{name: "middle", offset: 2, length: 3, data: "234"},
+		{name: "up to the end", offset: 7, data: "789"},
+		{name: "offset beyond the end", offset: 11, code: codes.OutOfRange},
+		{name: "length beyond the end", offset: 8, length: 5, code: codes.OutOfRange},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			req := &pb.DownloadFileRequest{FileId: file.GetId(), Offset: tc.offset, Length: tc.length}
+			stream, err := fileClient.Download(ctx, req)
+			require.NoError(t, err)
+
+			data, err := receiveDownload(stream)
+			require.Equal(t, tc.code, status.Code(err))
+			if tc.code == codes.OK {
+				require.Equal(t, tc.data, data)
+			}
+		})
+	}
+}
+
+// receiveDownload reads all chunks of a download stream
+func receiveDownload(stream pb.FileService_DownloadClient) (string, error) {
+	var data strings.Builder
+	for {
+		res, err := stream.Recv()
+		if err == io.EOF {
+			return data.String(), nil
+		}
+		if err != nil {
+			return "", err
+		}
+		data.Write(res.GetChunk())
+	}
+}
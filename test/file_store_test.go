@@ -0,0 +1,135 @@
+package service_test
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Nextasy01/grpc-file-service/pb"
+	"github.com/Nextasy01/grpc-file-service/service"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiskFileStoreReload(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	store, err := service.NewDiskFileStore(dir)
+	require.NoError(t, err)
+
+	file := &pb.File{Title: "notes.txt", Size: 5, Owner: &pb.Owner{Name: "testUser"}}
+	require.NoError(t, store.Save(file, strings.NewReader("hello")))
+	require.NoError(t, store.Close())
+
+	// a blob without metadata, as if the server crashed before journaling it
+	orphan := filepath.Join(dir, "orphan.txt")
+	require.NoError(t, os.WriteFile(orphan, []byte("lost"), 0644))
+
+	store, err = service.NewDiskFileStore(dir)
+	require.NoError(t, err)
+	defer store.Close()
+
+	found := store.Find(file.GetId())
+	require.NotNil(t, found)
+	require.Equal(t, "notes.txt", found.GetTitle())
+	require.Len(t, store.List("testUser"), 1)
+	require.NoFileExists(t, orphan)
+}
+
+func TestFileWriterAbort(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	store := service.NewInMemoryFileStore(dir)
+
+	writer, err := store.Create(&pb.File{Title: "partial.bin", Owner: &pb.Owner{Name: "testUser"}})
+	require.NoError(t, err)
+
+	_, err = writer.Write([]byte("some bytes"))
+	require.NoError(t, err)
+	require.NoError(t, writer.Abort())
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Empty(t, entries) // the partial upload is removed
+	require.Empty(t, store.List("testUser"))
+}
+
+func TestFileWriterTruncate(t *testing.T) {
+	t.Parallel()
+
+	store := service.NewInMemoryFileStore(t.TempDir())
+	file := &pb.File{Title: "notes.txt", Owner: &pb.Owner{Name: "testUser"}}
+	writer, err := store.Create(file)
+	require.NoError(t, err)
+
+	_, err = writer.Write([]byte("hello, lost"))
+	require.NoError(t, err)
+	require.NoError(t, writer.Truncate(5))
+	require.Error(t, writer.Truncate(6))
+	_, err = writer.Write([]byte(" world"))
+	require.NoError(t, err)
+	require.NoError(t, writer.Commit())
+
+	contents, err := store.Open(file.GetId())
+	require.NoError(t, err)
+	defer contents.Close()
+	data, err := io.ReadAll(contents)
+	require.NoError(t, err)
+	require.Equal(t, "hello world", string(data))
+	require.Equal(t, checksumOf("hello world"), file.GetChecksum())
+	require.EqualValues(t, 11, file.GetSize())
+}
+
+func TestDeduplication(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	store, err := service.NewDiskFileStore(dir)
+	require.NoError(t, err)
+	store.EnableDeduplication()
+
+	first := &pb.File{Title: "build.zip", Owner: &pb.Owner{Name: "alice"}}
+	second := &pb.File{Title: "copy.zip", Owner: &pb.Owner{Name: "bob"}}
+	require.NoError(t, store.Save(first, strings.NewReader("artifact")))
+	require.NoError(t, store.Save(second, strings.NewReader("artifact")))
+	require.NotEqual(t, first.GetId(), second.GetId())
+	require.Len(t, blobFiles(t, dir), 1) // both files share one blob
+
+	_, err = store.Delete(first.GetId())
+	require.NoError(t, err)
+	require.Len(t, blobFiles(t, dir), 1) // still used by the second file
+
+	require.NoError(t, store.Close())
+	store, err = service.NewDiskFileStore(dir)
+	require.NoError(t, err)
+	defer store.Close()
+
+	contents, err := store.Open(second.GetId())
+	require.NoError(t, err)
+	data, err := io.ReadAll(contents)
+	require.NoError(t, err)
+	require.NoError(t, contents.Close())
+	require.Equal(t, "artifact", string(data))
+
+	_, err = store.Delete(second.GetId())
+	require.NoError(t, err)
+	require.Empty(t, blobFiles(t, dir))
+}
+
+// blobFiles lists the stored contents, skipping the metadata journal
+func blobFiles(t *testing.T, dir string) []string {
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+
+	names := make([]string, 0)
+	for _, entry := range entries {
+		if !strings.HasPrefix(entry.Name(), ".") {
+			names = append(names, entry.Name())
+		}
+	}
+	return names
+}
@@ -0,0 +1,136 @@
+package service_test
+
+import (
+	"context"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Nextasy01/grpc-file-service/pb"
+	"github.com/Nextasy01/grpc-file-service/service"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestFolders(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	fileStore, err := service.NewDiskFileStore(dir)
+	require.NoError(t, err)
+
+	jwtManager := service.NewJWTManager("test-secret", time.Minute)
+	fileClient := newTestFileClient(t, startTestAuthFileServer(t, fileStore, jwtManager))
+	user := createUser(t, service.NewInMemoryUserStore(), "owner", "secret", "user")
+	ctx := authContext(t, jwtManager, user)
+	owner := &pb.Owner{Name: user.Username}
+
+	for _, name := range []string{"docs/a.txt", "docs/sub/b.txt", "c.txt"} {
+		file := &pb.File{Title: path.Base(name), Path: path.Dir(name), Owner: owner}
+		require.NoError(t, fileStore.Save(file, strings.NewReader(name)))
+	}
+
+	_, err = fileClient.CreateFolder(ctx, &pb.CreateFolderRequest{Owner: owner, Path: "empty/inner"})
+	require.NoError(t, err)
+	_, err = fileClient.CreateFolder(ctx, &pb.CreateFolderRequest{Owner: owner, Path: "docs"})
+	require.Equal(t, codes.AlreadyExists, status.Code(err))
+
+	require.Equal(t, []string{"c.txt", "docs/", "empty/"},
+		listFolder(t, ctx, fileClient, &pb.ListFilesRequest{User: owner, ChildrenOnly: true, IncludeFolders: true}))
+	require.Equal(t, []string{"docs/a.txt", "docs/sub/", "docs/sub/b.txt"},
+		listFolder(t, ctx, fileClient, &pb.ListFilesRequest{User: owner, Folder: "docs", IncludeFolders: true}))
+
+	res, err := fileClient.Move(ctx, &pb.MoveRequest{Owner: owner, Folder: "docs", Destination: "archive/docs"})
+	require.NoError(t, err)
+	require.EqualValues(t, 2, res.GetMoved())
+	_, err = fileClient.Move(ctx, &pb.MoveRequest{Owner: owner, Folder: "archive", Destination: "archive/docs/archive"})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = fileClient.Move(ctx, &pb.MoveRequest{Owner: owner, Folder: "archive", Destination: "empty"})
+	require.Equal(t, codes.AlreadyExists, status.Code(err))
+
+	c := fileStore.FindByPath(owner.GetName(), "c.txt")
+	_, err = fileClient.Move(ctx, &pb.MoveRequest{FileId: c.GetId(), Destination: "empty/inner"})
+	require.NoError(t, err)
+
+	_, err = fileClient.DeleteFolder(ctx, &pb.DeleteFolderRequest{Owner: owner, Path: "empty"})
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+	deleted, err := fileClient.DeleteFolder(ctx, &pb.DeleteFolderRequest{Owner: owner, Path: "empty", Recursive: true, Permanent: true})
+	require.NoError(t, err)
+	require.EqualValues(t, 1, deleted.GetFiles())
+
+	// the folders survive a restart
+	require.NoError(t, fileStore.Close())
+	fileStore, err = service.NewDiskFileStore(dir)
+	require.NoError(t, err)
+	defer fileStore.Close()
+
+	var folders []string
+	for _, folder := range fileStore.Folders(owner.GetName()) {
+		folders = append(folders, folder.GetPath())
+	}
+	require.Equal(t, []string{"archive", "archive/docs", "archive/docs/sub"}, folders)
+	require.NotNil(t, fileStore.FindByPath(owner.GetName(), "archive/docs/sub/b.txt"))
+}
+
+func TestMoveConflicts(t *testing.T) {
+	t.Parallel()
+
+	fileStore := service.NewInMemoryFileStore(t.TempDir())
+	jwtManager := service.NewJWTManager("test-secret", time.Minute)
+	fileClient := newTestFileClient(t, startTestAuthFileServer(t, fileStore, jwtManager))
+	user := createUser(t, service.NewInMemoryUserStore(), "owner", "secret", "user")
+	ctx := authContext(t, jwtManager, user)
+	owner := &pb.Owner{Name: user.Username}
+
+	// the folders only exist because files are stored in them
+	files := make(map[string]*pb.File)
+	for _, name := range []string{"a.txt", "notes", "docs/a.txt", "docs/b.txt", "drafts/a.txt"} {
+		file := &pb.File{Title: path.Base(name), Path: strings.TrimSuffix(path.Dir(name), "."), Owner: owner}
+		require.NoError(t, fileStore.Save(file, strings.NewReader(name)))
+		files[name] = file
+	}
+
+	_, err := fileClient.Move(ctx, &pb.MoveRequest{FileId: files["a.txt"].GetId(), Destination: "docs"})
+	require.Equal(t, codes.AlreadyExists, status.Code(err))
+	_, err = fileClient.Move(ctx, &pb.MoveRequest{FileId: files["docs/b.txt"].GetId(), Destination: "docs", Title: "a.txt"})
+	require.Equal(t, codes.AlreadyExists, status.Code(err))
+	_, err = fileClient.Move(ctx, &pb.MoveRequest{FileId: files["docs/b.txt"].GetId(), Destination: "docs", Title: "b.txt"})
+	require.NoError(t, err)
+
+	_, err = fileClient.Move(ctx, &pb.MoveRequest{Owner: owner, Folder: "drafts", Destination: "docs"})
+	require.Equal(t, codes.AlreadyExists, status.Code(err))
+	_, err = fileClient.Move(ctx, &pb.MoveRequest{Owner: owner, Folder: "drafts", Destination: "notes"})
+	require.Equal(t, codes.AlreadyExists, status.Code(err))
+
+	// every path still leads to its own file
+	for name, file := range files {
+		require.Equal(t, file.GetId(), fileStore.FindByPath(owner.GetName(), name).GetId(), name)
+	}
+}
+
+// listFolder returns the paths of the listed files and folders, folders end with a slash
+func listFolder(t *testing.T, ctx context.Context, fileClient pb.FileServiceClient, req *pb.ListFilesRequest) []string {
+	stream, err := fileClient.List(ctx, req)
+	require.NoError(t, err)
+
+	var names []string
+	for {
+		res, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+
+		if res.GetFolder() != nil {
+			names = append(names, res.GetFolder().GetPath()+"/")
+		} else {
+			names = append(names, path.Join(res.GetFile().GetPath(), res.GetFile().GetTitle()))
+		}
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,71 @@
+package service_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Nextasy01/grpc-file-service/pb"
+	"github.com/Nextasy01/grpc-file-service/service"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestGetFile(t *testing.T) {
+	t.Parallel()
+
+	fileStore := service.NewInMemoryFileStore(t.TempDir())
+	jwtManager := service.NewJWTManager("test-secret", time.Minute)
+	fileClient := newTestFileClient(t, startTestAuthFileServer(t, fileStore, jwtManager))
+	userStore := service.NewInMemoryUserStore()
+	owner := createUser(t, userStore, "alice", "secret", "user")
+	other := createUser(t, userStore, "bob", "secret", "user")
+	ownerCtx := authContext(t, jwtManager, owner)
+	otherCtx := authContext(t, jwtManager, other)
+
+	notes := &pb.File{Title: "notes.txt", Owner: &pb.Owner{Name: owner.Username}, Labels: map[string]string{"env": "prod"}}
+	require.NoError(t, fileStore.Save(notes, strings.NewReader("remember the milk")))
+	plan, err := uploadFile(ownerCtx, fileClient, &pb.Owner{Name: owner.Username}, "plan.txt", "step one")
+	require.NoError(t, err)
+
+	file, err := fileClient.GetFile(ownerCtx, &pb.GetFileRequest{FileId: notes.GetId()})
+	require.NoError(t, err)
+	require.Equal(t, "notes.txt", file.GetTitle())
+	require.EqualValues(t, len("remember the milk"), file.GetSize())
+	require.Equal(t, "text/plain; charset=utf-8", file.GetContentType())
+	require.Equal(t, map[string]string{"env": "prod"}, file.GetLabels())
+	require.Len(t, file.GetChecksum(), 64)
+
+	_, err = fileClient.GetFile(ownerCtx, &pb.GetFileRequest{FileId: "missing"})
+	require.Equal(t, codes.NotFound, status.Code(err))
+	_, err = fileClient.GetFile(ownerCtx, &pb.GetFileRequest{})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = fileClient.GetFile(otherCtx, &pb.GetFileRequest{FileId: notes.GetId()})
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	// files are returned in the requested order, duplicates included
+	ids := []string{plan.GetFile().GetId(), notes.GetId(), plan.GetFile().GetId()}
+	batch, err := fileClient.BatchGetFiles(ownerCtx, &pb.BatchGetFilesRequest{FileIds: ids})
+	require.NoError(t, err)
+	require.Len(t, batch.GetFiles(), 3)
+	for i, file := range batch.GetFiles() {
+		require.Equal(t, ids[i], file.GetId())
+	}
+
+	// a single file which can't be read fails the whole batch
+	_, err = fileClient.BatchGetFiles(ownerCtx, &pb.BatchGetFilesRequest{FileIds: []string{notes.GetId(), "missing"}})
+	require.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = fileClient.ShareFile(ownerCtx, &pb.ShareFileRequest{FileId: notes.GetId(), User: &pb.Owner{Name: other.Username}, Permission: pb.Permission_READ})
+	require.NoError(t, err)
+	_, err = fileClient.BatchGetFiles(otherCtx, &pb.BatchGetFilesRequest{FileIds: ids})
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+	require.Contains(t, status.Convert(err).Message(), plan.GetFile().GetId())
+	batch, err = fileClient.BatchGetFiles(otherCtx, &pb.BatchGetFilesRequest{FileIds: []string{notes.GetId()}})
+	require.NoError(t, err)
+	require.Equal(t, "notes.txt", batch.GetFiles()[0].GetTitle())
+
+	_, err = fileClient.BatchGetFiles(ownerCtx, &pb.BatchGetFilesRequest{FileIds: make([]string, 1001)})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
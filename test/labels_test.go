@@ -0,0 +1,80 @@
+package service_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Nextasy01/grpc-file-service/pb"
+	"github.com/Nextasy01/grpc-file-service/service"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+)
+
+func TestLabels(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	fileStore, err := service.NewDiskFileStore(dir)
+	require.NoError(t, err)
+
+	jwtManager := service.NewJWTManager("test-secret", time.Minute)
+	fileClient := newTestFileClient(t, startTestAuthFileServer(t, fileStore, jwtManager))
+	userStore := service.NewInMemoryUserStore()
+	user := createUser(t, userStore, "builder", "secret", "user")
+	ctx := authContext(t, jwtManager, user)
+	owner := &pb.Owner{Name: user.Username}
+
+	stream, err := fileClient.Upload(ctx)
+	require.NoError(t, err)
+	require.NoError(t, stream.Send(&pb.UploadFileRequest{File: &pb.File{
+		Title:  "app.log",
+		Owner:  owner,
+		Labels: map[string]string{"build": "42", "env": "staging"},
+		Tags:   []string{"nightly", "logs", "nightly"},
+	}}))
+	require.NoError(t, stream.Send(&pb.UploadFileRequest{Chunk: []byte("started")}))
+	res, err := stream.CloseAndRecv()
+	require.NoError(t, err)
+	require.Equal(t, []string{"nightly", "logs"}, res.GetFile().GetTags())
+	id := res.GetFile().GetId()
+
+	download, err := fileClient.Download(ctx, &pb.DownloadFileRequest{FileId: id})
+	require.NoError(t, err)
+	md, err := download.Header()
+	require.NoError(t, err)
+	labels, tags := service.LabelsFromMetadata(md)
+	require.Equal(t, map[string]string{"build": "42", "env": "staging"}, labels)
+	require.Equal(t, []string{"nightly", "logs"}, tags)
+
+	// labels not in the update are removed, the tags are kept
+	updated, err := fileClient.UpdateMetadata(ctx, &pb.UpdateMetadataRequest{
+		FileId:     id,
+		File:       &pb.File{Labels: map[string]string{"env": "prod", "project": "ignored"}},
+		UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"labels.env", "labels.build"}},
+	})
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"env": "prod"}, updated.GetFile().GetLabels())
+	require.Equal(t, []string{"nightly", "logs"}, updated.GetFile().GetTags())
+
+	for _, req := range []*pb.UpdateMetadataRequest{
+		{FileId: id, UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"title"}}},
+		{FileId: id, File: &pb.File{Labels: map[string]string{"Env": "prod"}}, UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"labels"}}},
+		{FileId: id, File: &pb.File{Tags: []string{"a,b"}}},
+	} {
+		_, err = fileClient.UpdateMetadata(ctx, req)
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	}
+
+	other := createUser(t, userStore, "other", "secret", "user")
+	_, err = fileClient.UpdateMetadata(authContext(t, jwtManager, other), &pb.UpdateMetadataRequest{FileId: id})
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	// the labels are persisted
+	require.NoError(t, fileStore.Close())
+	fileStore, err = service.NewDiskFileStore(dir)
+	require.NoError(t, err)
+	defer fileStore.Close()
+	require.Equal(t, map[string]string{"env": "prod"}, fileStore.Find(id).GetLabels())
+}
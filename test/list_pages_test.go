@@ -0,0 +1,99 @@
+package service_test
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Nextasy01/grpc-file-service/pb"
+	"github.com/Nextasy01/grpc-file-service/service"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestListPages(t *testing.T) {
+	t.Parallel()
+
+	fileStore := service.NewInMemoryFileStore(t.TempDir())
+	jwtManager := service.NewJWTManager("test-secret", time.Minute)
+	fileClient := newTestFileClient(t, startTestAuthFileServer(t, fileStore, jwtManager))
+	owner := &pb.Owner{Name: "pager"}
+
+	save := func(title string, size int) *pb.File {
+		file := &pb.File{Title: title, Owner: owner}
+		require.NoError(t, fileStore.Save(file, strings.NewReader(strings.Repeat("x", size))))
+		return file
+	}
+	save("a.txt", 5)
+	b := save("b.txt", 4)
+	save("c.txt", 3)
+	save("d.txt", 2)
+	save("e.txt", 1)
+
+	ctx := authContext(t, jwtManager, createUser(t, service.NewInMemoryUserStore(), "pager", "secret", "user"))
+	req := &pb.ListFilesRequest{User: owner, PageSize: 2, OrderBy: "size desc"}
+	files, token, err := listPage(ctx, fileClient, req)
+	require.NoError(t, err)
+	require.Equal(t, []string{"a.txt", "b.txt"}, files)
+	require.NotEmpty(t, token)
+
+	// files added or removed before the position of the token don't shift the next pages
+	save("big.txt", 10)
+	_, err = fileStore.Delete(b.GetId())
+	require.NoError(t, err)
+
+	req.PageToken = token
+	files, token, err = listPage(ctx, fileClient, req)
+	require.NoError(t, err)
+	require.Equal(t, []string{"c.txt", "d.txt"}, files)
+
+	req.PageToken = token
+	files, token, err = listPage(ctx, fileClient, req)
+	require.NoError(t, err)
+	require.Equal(t, []string{"e.txt"}, files)
+	require.Empty(t, token)
+
+	files, _, err = listPage(ctx, fileClient, &pb.ListFilesRequest{User: owner})
+	require.NoError(t, err)
+	require.Equal(t, []string{"a.txt", "big.txt", "c.txt", "d.txt", "e.txt"}, files)
+
+	for _, req := range []*pb.ListFilesRequest{
+		{User: owner, OrderBy: "color"},
+		{User: owner, OrderBy: "size sideways"},
+		{User: owner, PageToken: "garbage"},
+		{User: owner, PageToken: req.GetPageToken(), OrderBy: "name"},
+		{User: owner, PageToken: req.GetPageToken(), OrderBy: "size desc", SharedWithMe: true},
+	} {
+		_, _, err = listPage(ctx, fileClient, req)
+		require.Equal(t, codes.InvalidArgument, status.Code(err), req.String())
+	}
+
+	// users only list their own files
+	_, _, err = listPage(ctx, fileClient, &pb.ListFilesRequest{User: &pb.Owner{Name: "other"}})
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
+// listPage returns the names of the listed files and the token of the next page
+func listPage(ctx context.Context, fileClient pb.FileServiceClient, req *pb.ListFilesRequest) ([]string, string, error) {
+	stream, err := fileClient.List(ctx, req)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var files []string
+	token := ""
+	for {
+		res, err := stream.Recv()
+		if err == io.EOF {
+			return files, token, nil
+		}
+		if err != nil {
+			return nil, "", err
+		}
+		files = append(files, res.GetFile().GetTitle())
+		token = res.GetNextPageToken()
+	}
+}
@@ -0,0 +1,205 @@
+package service_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Nextasy01/grpc-file-service/pb"
+	"github.com/Nextasy01/grpc-file-service/service"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestMultipartUpload(t *testing.T) {
+	t.Parallel()
+
+	partsDir := t.TempDir()
+	fileStore := service.NewInMemoryFileStore(t.TempDir())
+	quotas := service.NewQuotaManager(fileStore, service.NewInMemoryUserStore(), nil)
+	multipartUploads := service.NewMultipartUploadStore(partsDir, quotas, time.Hour)
+	fileServer := service.NewFileServer(fileStore, service.NewUploadSessionStore(fileStore, time.Hour), multipartUploads, quotas)
+	t.Cleanup(fileServer.Close)
+
+	jwtManager := service.NewJWTManager("test-secret", time.Minute)
+	fileClient := newTestFileClient(t, serveTestFileServer(t, fileServer, testAuthServerOptions(jwtManager)...))
+	user := createUser(t, service.NewInMemoryUserStore(), "uploader", "secret", "user")
+	ctx := authContext(t, jwtManager, user)
+
+	data := []string{"first part,", "second part,", "third part"}
+	upload, err := fileClient.InitiateMultipartUpload(ctx, &pb.InitiateMultipartUploadRequest{
+		File: &pb.File{Title: "parts.txt", Owner: &pb.Owner{Name: user.Username}, Checksum: checksumOf("first part,second part,third part")},
+	})
+	require.NoError(t, err)
+
+	// the parts are sent concurrently and in any order
+	var wg sync.WaitGroup
+	responses := make([]*pb.UploadPartResponse, len(data))
+	errs := make([]error, len(data))
+	for i := len(data) - 1; i >= 0; i-- {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			responses[i], errs[i] = uploadPart(ctx, fileClient, upload.GetUploadId(), uint32(i+1), data[i])
+		}(i)
+	}
+	wg.Wait()
+	for i := range data {
+		require.NoError(t, errs[i])
+		require.Equal(t, checksumOf(data[i]), responses[i].GetChecksum())
+	}
+
+	_, err = fileClient.CompleteMultipartUpload(ctx, &pb.CompleteMultipartUploadRequest{
+		UploadId: upload.GetUploadId(),
+		Parts:    []*pb.CompletedPart{{PartNumber: 1}, {PartNumber: 2}, {PartNumber: 4}},
+	})
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	_, err = fileClient.CompleteMultipartUpload(ctx, &pb.CompleteMultipartUploadRequest{
+		UploadId: upload.GetUploadId(),
+		Parts:    []*pb.CompletedPart{{PartNumber: 1, Checksum: checksumOf("other data")}, {PartNumber: 2}, {PartNumber: 3}},
+	})
+	require.Equal(t, codes.DataLoss, status.Code(err))
+
+	res, err := fileClient.CompleteMultipartUpload(ctx, &pb.CompleteMultipartUploadRequest{
+		UploadId: upload.GetUploadId(),
+		Parts: []*pb.CompletedPart{
+			{PartNumber: 1, Checksum: checksumOf(data[0])},
+			{PartNumber: 2, Checksum: checksumOf(data[1])},
+			{PartNumber: 3, Checksum: checksumOf(data[2])},
+		},
+	})
+	require.NoError(t, err)
+	require.EqualValues(t, 33, res.GetFile().GetSize())
+
+	contents, err := fileStore.Open(res.GetFile().GetId())
+	require.NoError(t, err)
+	defer contents.Close()
+	stored, err := io.ReadAll(contents)
+	require.NoError(t, err)
+	require.Equal(t, "first part,second part,third part", string(stored))
+
+	// the parts are removed once the file is assembled
+	entries, err := os.ReadDir(partsDir)
+	require.NoError(t, err)
+	require.Empty(t, entries)
+	_, err = uploadPart(ctx, fileClient, upload.GetUploadId(), 1, "late")
+	require.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestMultipartUploadQuota(t *testing.T) {
+	t.Parallel()
+
+	fileStore := service.NewInMemoryFileStore(t.TempDir())
+	userStore := service.NewInMemoryUserStore()
+	user := createUser(t, userStore, "uploader", "secret", "user")
+	quotas := service.NewQuotaManager(fileStore, userStore, map[string]uint64{"user": 20})
+	multipartUploads := service.NewMultipartUploadStore(t.TempDir(), quotas, time.Hour)
+	fileServer := service.NewFileServer(fileStore, service.NewUploadSessionStore(fileStore, time.Hour), multipartUploads, quotas)
+	t.Cleanup(fileServer.Close)
+
+	jwtManager := service.NewJWTManager("test-secret", time.Minute)
+	fileClient := newTestFileClient(t, serveTestFileServer(t, fileServer, testAuthServerOptions(jwtManager)...))
+	ctx := authContext(t, jwtManager, user)
+	owner := &pb.Owner{Name: user.Username}
+
+	upload, err := fileClient.InitiateMultipartUpload(ctx, &pb.InitiateMultipartUploadRequest{File: &pb.File{Title: "big.bin", Owner: owner}})
+	require.NoError(t, err)
+
+	// the saved parts count against the quota together
+	_, err = uploadPart(ctx, fileClient, upload.GetUploadId(), 1, "12345678")
+	require.NoError(t, err)
+	_, err = uploadPart(ctx, fileClient, upload.GetUploadId(), 2, "12345678")
+	require.NoError(t, err)
+	_, err = uploadPart(ctx, fileClient, upload.GetUploadId(), 3, "12345678")
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+	_, err = uploadFile(ctx, fileClient, owner, "small.txt", "12345")
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	// the file assembled from the reserved parts fits
+	res, err := fileClient.CompleteMultipartUpload(ctx, &pb.CompleteMultipartUploadRequest{
+		UploadId: upload.GetUploadId(),
+		Parts:    []*pb.CompletedPart{{PartNumber: 1}, {PartNumber: 2}},
+	})
+	require.NoError(t, err)
+	require.EqualValues(t, 16, res.GetFile().GetSize())
+	_, err = uploadFile(ctx, fileClient, owner, "small.txt", "1234")
+	require.NoError(t, err)
+
+	// aborting an upload gives its parts back
+	_, err = fileClient.Delete(ctx, &pb.DeleteFileRequest{FileId: res.GetFile().GetId(), Permanent: true})
+	require.NoError(t, err)
+	upload, err = fileClient.InitiateMultipartUpload(ctx, &pb.InitiateMultipartUploadRequest{File: &pb.File{Title: "big.bin", Owner: owner}})
+	require.NoError(t, err)
+	_, err = uploadPart(ctx, fileClient, upload.GetUploadId(), 1, "1234567890123456")
+	require.NoError(t, err)
+	_, err = fileClient.AbortMultipartUpload(ctx, &pb.AbortMultipartUploadRequest{UploadId: upload.GetUploadId()})
+	require.NoError(t, err)
+	_, err = uploadFile(ctx, fileClient, owner, "large.txt", "1234567890123456")
+	require.NoError(t, err)
+}
+
+func uploadPart(ctx context.Context, fileClient pb.FileServiceClient, uploadId string, number uint32, data string) (*pb.UploadPartResponse, error) {
+	stream, err := fileClient.UploadPart(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = stream.Send(&pb.UploadPartRequest{UploadId: uploadId, PartNumber: number})
+	if err == nil {
+		err = stream.Send(&pb.UploadPartRequest{Chunk: []byte(data)})
+	}
+
+	// a failed Send returns io.EOF, the actual error comes with the response
+	return stream.CloseAndRecv()
+}
+
+func checksumOf(data string) string {
+	sum := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestMultipartUploadAbortWhileReceiving(t *testing.T) {
+	t.Parallel()
+
+	fileStore := service.NewInMemoryFileStore(t.TempDir())
+	userStore := service.NewInMemoryUserStore()
+	user := createUser(t, userStore, "uploader", "secret", "user")
+	quotas := service.NewQuotaManager(fileStore, userStore, map[string]uint64{"user": 100})
+	fileServer := service.NewFileServer(fileStore, service.NewUploadSessionStore(fileStore, time.Hour), service.NewMultipartUploadStore(t.TempDir(), quotas, time.Hour), quotas)
+	t.Cleanup(fileServer.Close)
+
+	jwtManager := service.NewJWTManager("test-secret", time.Minute)
+	fileClient := newTestFileClient(t, serveTestFileServer(t, fileServer, testAuthServerOptions(jwtManager)...))
+	ctx := authContext(t, jwtManager, user)
+
+	upload, err := fileClient.InitiateMultipartUpload(ctx, &pb.InitiateMultipartUploadRequest{File: &pb.File{Title: "big.bin", Owner: &pb.Owner{Name: user.Username}}})
+	require.NoError(t, err)
+	abort := &pb.AbortMultipartUploadRequest{UploadId: upload.GetUploadId()}
+
+	stream, err := fileClient.UploadPart(ctx)
+	require.NoError(t, err)
+	require.NoError(t, stream.Send(&pb.UploadPartRequest{UploadId: upload.GetUploadId(), PartNumber: 1}))
+	require.NoError(t, stream.Send(&pb.UploadPartRequest{Chunk: []byte("12345678")}))
+	require.Eventually(t, func() bool {
+		return quotas.Reserved(user.Username) == 8
+	}, time.Second, 10*time.Millisecond)
+
+	// the part in flight would keep its reservation after the upload is gone
+	_, err = fileClient.AbortMultipartUpload(ctx, abort)
+	require.Equal(t, codes.Aborted, status.Code(err))
+
+	_, err = stream.CloseAndRecv()
+	require.NoError(t, err)
+	require.EqualValues(t, 8, quotas.Reserved(user.Username))
+
+	_, err = fileClient.AbortMultipartUpload(ctx, abort)
+	require.NoError(t, err)
+	require.Zero(t, quotas.Reserved(user.Username))
+}
@@ -0,0 +1,64 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Nextasy01/grpc-file-service/pb"
+	"github.com/Nextasy01/grpc-file-service/service"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestQuota(t *testing.T) {
+	t.Parallel()
+
+	fileStore := service.NewInMemoryFileStore(t.TempDir())
+	userStore := service.NewInMemoryUserStore()
+	user := createUser(t, userStore, "alice", "secret", "user")
+
+	quotas := service.NewQuotaManager(fileStore, userStore, map[string]uint64{"user": 10})
+	fileServer := service.NewFileServer(fileStore, service.NewUploadSessionStore(fileStore, time.Hour), service.NewMultipartUploadStore(t.TempDir(), quotas, time.Hour), quotas)
+	t.Cleanup(fileServer.Close)
+	fileClient := newTestFileClient(t, serveTestFileServer(t, fileServer))
+	owner := &pb.Owner{Name: user.Username}
+
+	_, err := uploadFile(context.Background(), fileClient, owner, "first.txt", "123456")
+	require.NoError(t, err)
+
+	_, err = uploadFile(context.Background(), fileClient, owner, "second.txt", "123456")
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	usage, err := fileClient.GetUsage(context.Background(), &pb.GetUsageRequest{User: owner})
+	require.NoError(t, err)
+	require.EqualValues(t, 6, usage.GetUsedBytes())
+	require.EqualValues(t, 1, usage.GetFileCount())
+	require.EqualValues(t, 10, usage.GetLimitBytes())
+
+	quotas.SetUserLimit(user.Username, 100)
+	_, err = uploadFile(context.Background(), fileClient, owner, "second.txt", "123456")
+	require.NoError(t, err)
+}
+
+// uploadFile uploads data in chunks of 4 bytes
+func uploadFile(ctx context.Context, fileClient pb.FileServiceClient, owner *pb.Owner, title, data string) (*pb.UploadFileResponse, error) {
+	stream, err := fileClient.Upload(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = stream.Send(&pb.UploadFileRequest{File: &pb.File{Title: title, Owner: owner}})
+	for len(data) > 0 && err == nil {
+		n := 4
+		if len(data) < n {
+			n = len(data)
+		}
+		err = stream.Send(&pb.UploadFileRequest{Chunk: []byte(data[:n])})
+		data = data[n:]
+	}
+
+	// a failed Send returns io.EOF, the actual error comes with the response
+	return stream.CloseAndRecv()
+}
@@ -0,0 +1,135 @@
+package service_test
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Nextasy01/grpc-file-service/pb"
+	"github.com/Nextasy01/grpc-file-service/service"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestSearch(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	fileStore, err := service.NewDiskFileStore(dir)
+	require.NoError(t, err)
+
+	// the store sets the creation time, so the files are told apart by when they were saved
+	saved := make([]time.Time, 0)
+	for i, file := range []*pb.File{
+		{Title: "build.log", Path: "ci", Labels: map[string]string{"env": "prod", "build": "41"}},
+		{Title: "Build-42.LOG", Path: "ci", Labels: map[string]string{"env": "staging", "build": "42"}},
+		{Title: "report.csv", Labels: map[string]string{"env": "prod"}},
+		{Title: "photo.png"},
+	} {
+		file.Owner = &pb.Owner{Name: "searcher"}
+		saved = append(saved, time.Now())
+		require.NoError(t, fileStore.Save(file, strings.NewReader(strings.Repeat("x", 10*(i+1)))))
+	}
+	other := &pb.File{Title: "build.log", Owner: &pb.Owner{Name: "other"}, Labels: map[string]string{"env": "prod"}}
+	require.NoError(t, fileStore.Save(other, strings.NewReader("other")))
+
+	// the index is rebuilt when the store is opened again
+	require.NoError(t, fileStore.Close())
+	fileStore, err = service.NewDiskFileStore(dir)
+	require.NoError(t, err)
+	defer fileStore.Close()
+
+	jwtManager := service.NewJWTManager("test-secret", time.Minute)
+	fileClient := newTestFileClient(t, startTestAuthFileServer(t, fileStore, jwtManager))
+	userStore := service.NewInMemoryUserStore()
+	ctx := authContext(t, jwtManager, createUser(t, userStore, "searcher", "secret", "user"))
+	owner := &pb.Owner{Name: "searcher"}
+
+	testCases := []struct {
+		name  string
+		req   *pb.SearchRequest
+		files []string
+	}{
+		{"glob", &pb.SearchRequest{Owner: owner, Name: "*.log"}, []string{"ci/Build-42.LOG", "ci/build.log"}},
+		{"substring", &pb.SearchRequest{Owner: owner, Name: "port"}, []string{"report.csv"}},
+		{"size", &pb.SearchRequest{Owner: owner, MinSize: 20, MaxSize: 30}, []string{"ci/Build-42.LOG", "report.csv"}},
+		{"created", &pb.SearchRequest{Owner: owner, Created: &pb.TimeRange{
+			Start: timestamppb.New(saved[1]),
+			End:   timestamppb.New(saved[3]),
+		}}, []string{"ci/Build-42.LOG", "report.csv"}},
+		{"content type", &pb.SearchRequest{Owner: owner, ContentType: "text/"}, []string{"ci/Build-42.LOG", "ci/build.log", "report.csv"}},
+		{"label", &pb.SearchRequest{Owner: owner, Labels: map[string]string{"env": "prod"}}, []string{"ci/build.log", "report.csv"}},
+		{"any label value", &pb.SearchRequest{Owner: owner, Labels: map[string]string{"build": ""}, Name: "*42*"}, []string{"ci/Build-42.LOG"}},
+		{"everything", &pb.SearchRequest{Owner: owner}, []string{"ci/Build-42.LOG", "ci/build.log", "photo.png", "report.csv"}},
+	}
+	for _, tc := range testCases {
+		files, err := search(ctx, fileClient, tc.req)
+		require.NoError(t, err, tc.name)
+		require.Equal(t, tc.files, files, tc.name)
+	}
+
+	// the index follows changes of the files
+	found := fileStore.FindByPath("searcher", "report.csv")
+	_, err = fileStore.UpdateMetadata(found.GetId(), func(file *pb.File) error {
+		file.Labels = map[string]string{"env": "dev"}
+		return nil
+	})
+	require.NoError(t, err)
+	_, err = fileStore.Trash(fileStore.FindByPath("searcher", "ci/build.log").GetId())
+	require.NoError(t, err)
+	files, err := search(ctx, fileClient, &pb.SearchRequest{Owner: owner, Labels: map[string]string{"env": "prod"}})
+	require.NoError(t, err)
+	require.Empty(t, files)
+
+	_, err = search(ctx, fileClient, &pb.SearchRequest{Owner: owner, Name: "[a-"})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = search(ctx, fileClient, &pb.SearchRequest{Owner: &pb.Owner{Name: "other"}})
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	// times sent by the client are ignored
+	stream, err := fileClient.Upload(ctx)
+	require.NoError(t, err)
+	forged := timestamppb.New(time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, stream.Send(&pb.UploadFileRequest{File: &pb.File{Title: "forged.txt", Owner: owner, CreatedAt: forged, UpdatedAt: forged}}))
+	require.NoError(t, stream.Send(&pb.UploadFileRequest{Chunk: []byte("backdated")}))
+	uploaded, err := stream.CloseAndRecv()
+	require.NoError(t, err)
+	require.False(t, uploaded.GetFile().GetCreatedAt().AsTime().Before(saved[0]))
+	require.Equal(t, uploaded.GetFile().GetCreatedAt().AsTime(), uploaded.GetFile().GetUpdatedAt().AsTime())
+	files, err = search(ctx, fileClient, &pb.SearchRequest{Owner: owner, Created: &pb.TimeRange{End: timestamppb.New(saved[0])}})
+	require.NoError(t, err)
+	require.Empty(t, files)
+	files, err = search(ctx, fileClient, &pb.SearchRequest{Owner: owner, Updated: &pb.TimeRange{Start: timestamppb.Now()}})
+	require.NoError(t, err)
+	require.Empty(t, files)
+
+	// admins may search the files of every user
+	admin := authContext(t, jwtManager, createUser(t, userStore, "root", "secret", "admin"))
+	files, err = search(admin, fileClient, &pb.SearchRequest{Name: "*.log"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"build.log", "ci/Build-42.LOG"}, files)
+}
+
+// search returns the paths of the files found
+func search(ctx context.Context, fileClient pb.FileServiceClient, req *pb.SearchRequest) ([]string, error) {
+	stream, err := fileClient.Search(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]string, 0)
+	for {
+		res, err := stream.Recv()
+		if err == io.EOF {
+			return files, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, strings.TrimPrefix(res.GetFile().GetPath()+"/"+res.GetFile().GetTitle(), "/"))
+	}
+}
@@ -0,0 +1,106 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Nextasy01/grpc-file-service/pb"
+	"github.com/Nextasy01/grpc-file-service/service"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+func TestShareLinks(t *testing.T) {
+	t.Parallel()
+
+	fileStore := service.NewInMemoryFileStore(t.TempDir())
+	jwtManager := service.NewJWTManager("test-secret", time.Minute)
+	fileServer := newTestFileServer(t, fileStore)
+	fileServer.EnableShareLinks(service.NewShareLinkStore(), jwtManager)
+	fileClient := newTestFileClient(t, serveTestFileServer(t, fileServer, testAuthServerOptions(jwtManager)...))
+	userStore := service.NewInMemoryUserStore()
+	owner := createUser(t, userStore, "alice", "secret", "user")
+	other := createUser(t, userStore, "bob", "secret", "user")
+	admin := createUser(t, userStore, "carol", "secret", "admin")
+	ownerCtx := authContext(t, jwtManager, owner)
+	otherCtx := authContext(t, jwtManager, other)
+
+	res, err := uploadFile(ownerCtx, fileClient, &pb.Owner{Name: owner.Username}, "invoice.pdf", "amount due")
+	require.NoError(t, err)
+	id := res.GetFile().GetId()
+
+	// downloads through links need no account
+	download := func(token, password string) (string, error) {
+		stream, err := fileClient.DownloadShared(context.Background(), &pb.DownloadSharedRequest{Token: token, Password: password})
+		if err != nil {
+			return "", err
+		}
+		return receiveDownload(stream)
+	}
+
+	_, err = fileClient.CreateShareLink(authContext(t, jwtManager, admin), &pb.CreateShareLinkRequest{FileId: id})
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+	_, err = fileClient.CreateShareLink(ownerCtx, &pb.CreateShareLinkRequest{FileId: id, ExpiresIn: durationpb.New(31 * 24 * time.Hour)})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	link, err := fileClient.CreateShareLink(ownerCtx, &pb.CreateShareLinkRequest{FileId: id, MaxDownloads: 2, Password: "open sesame"})
+	require.NoError(t, err)
+	require.NotEmpty(t, link.GetToken())
+	require.True(t, link.GetHasPassword())
+
+	_, err = download(link.GetToken(), "wrong")
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+	data, err := download(link.GetToken(), "open sesame")
+	require.NoError(t, err)
+	require.Equal(t, "amount due", data)
+
+	// access tokens of users aren't share links
+	accessToken, err := jwtManager.Generate(owner)
+	require.NoError(t, err)
+	_, err = download(accessToken, "")
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	links, err := fileClient.ListShareLinks(ownerCtx, &pb.ListShareLinksRequest{Owner: &pb.Owner{Name: owner.Username}})
+	require.NoError(t, err)
+	require.Len(t, links.GetLinks(), 1)
+	require.EqualValues(t, 1, links.GetLinks()[0].GetDownloads())
+	require.Empty(t, links.GetLinks()[0].GetToken())
+	_, err = fileClient.ListShareLinks(otherCtx, &pb.ListShareLinksRequest{Owner: &pb.Owner{Name: owner.Username}})
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = download(link.GetToken(), "open sesame")
+	require.NoError(t, err)
+	_, err = download(link.GetToken(), "open sesame")
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	// revoked links stop working even though their tokens haven't expired
+	open, err := fileClient.CreateShareLink(ownerCtx, &pb.CreateShareLinkRequest{FileId: id})
+	require.NoError(t, err)
+	_, err = download(open.GetToken(), "")
+	require.NoError(t, err)
+
+	revoke := &pb.RevokeShareLinkRequest{LinkId: open.GetLinkId()}
+	_, err = fileClient.RevokeShareLink(otherCtx, revoke)
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+	_, err = fileClient.RevokeShareLink(ownerCtx, revoke)
+	require.NoError(t, err)
+	_, err = download(open.GetToken(), "")
+	require.Equal(t, codes.NotFound, status.Code(err))
+	_, err = fileClient.RevokeShareLink(ownerCtx, revoke)
+	require.Equal(t, codes.NotFound, status.Code(err))
+
+	// links are kept in memory only, a restarted server tells they were lost
+	restarted := newTestFileServer(t, fileStore)
+	restarted.EnableShareLinks(service.NewShareLinkStore(), jwtManager)
+	restartedClient := newTestFileClient(t, serveTestFileServer(t, restarted, testAuthServerOptions(jwtManager)...))
+	lost, err := fileClient.CreateShareLink(ownerCtx, &pb.CreateShareLinkRequest{FileId: id})
+	require.NoError(t, err)
+	stream, err := restartedClient.DownloadShared(context.Background(), &pb.DownloadSharedRequest{Token: lost.GetToken()})
+	require.NoError(t, err)
+	_, err = receiveDownload(stream)
+	require.Equal(t, codes.NotFound, status.Code(err))
+	require.Contains(t, status.Convert(err).Message(), "restarted")
+}
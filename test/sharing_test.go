@@ -0,0 +1,118 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Nextasy01/grpc-file-service/pb"
+	"github.com/Nextasy01/grpc-file-service/service"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestSharing(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	fileStore, err := service.NewDiskFileStore(dir)
+	require.NoError(t, err)
+
+	jwtManager := service.NewJWTManager("test-secret", time.Minute)
+	fileClient := newTestFileClient(t, startTestAuthFileServer(t, fileStore, jwtManager))
+	userStore := service.NewInMemoryUserStore()
+	owner := createUser(t, userStore, "alice", "secret", "user")
+	reader := createUser(t, userStore, "bob", "secret", "user")
+	admin := createUser(t, userStore, "carol", "secret", "admin")
+	ownerCtx := authContext(t, jwtManager, owner)
+	readerCtx := authContext(t, jwtManager, reader)
+	adminCtx := authContext(t, jwtManager, admin)
+
+	res, err := uploadFile(ownerCtx, fileClient, &pb.Owner{Name: owner.Username}, "report.txt", "quarterly numbers")
+	require.NoError(t, err)
+	id := res.GetFile().GetId()
+
+	download := func(ctx context.Context) (string, error) {
+		stream, err := fileClient.Download(ctx, &pb.DownloadFileRequest{FileId: id})
+		if err != nil {
+			return "", err
+		}
+		return receiveDownload(stream)
+	}
+	shared := func(ctx context.Context, user *service.User) []string {
+		files, _, err := listPage(ctx, fileClient, &pb.ListFilesRequest{User: &pb.Owner{Name: user.Username}, SharedWithMe: true})
+		require.NoError(t, err)
+		return files
+	}
+	share := func(ctx context.Context, username string, permission pb.Permission) (*pb.ShareFileResponse, error) {
+		return fileClient.ShareFile(ctx, &pb.ShareFileRequest{FileId: id, User: &pb.Owner{Name: username}, Permission: permission})
+	}
+
+	// neither other users nor admins get the contents of a file which isn't shared with them
+	_, err = download(readerCtx)
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+	_, err = download(adminCtx)
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+	_, err = share(adminCtx, admin.Username, pb.Permission_READ)
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+	_, err = share(ownerCtx, owner.Username, pb.Permission_READ)
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = share(ownerCtx, reader.Username, pb.Permission_PERMISSION_UNSPECIFIED)
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+	require.Empty(t, shared(readerCtx, reader))
+
+	// read access lets the user download the file but not change it
+	_, err = share(ownerCtx, reader.Username, pb.Permission_READ)
+	require.NoError(t, err)
+	data, err := download(readerCtx)
+	require.NoError(t, err)
+	require.Equal(t, "quarterly numbers", data)
+	require.Equal(t, []string{"report.txt"}, shared(readerCtx, reader))
+
+	// only the owner sees who else the file is shared with
+	_, err = share(ownerCtx, "dave", pb.Permission_READ)
+	require.NoError(t, err)
+	found, err := fileClient.GetFile(readerCtx, &pb.GetFileRequest{FileId: id})
+	require.NoError(t, err)
+	require.Empty(t, found.GetGrants())
+	stream, err := fileClient.List(readerCtx, &pb.ListFilesRequest{User: &pb.Owner{Name: reader.Username}, SharedWithMe: true})
+	require.NoError(t, err)
+	listed, err := stream.Recv()
+	require.NoError(t, err)
+	require.Empty(t, listed.GetFile().GetGrants())
+	found, err = fileClient.GetFile(ownerCtx, &pb.GetFileRequest{FileId: id})
+	require.NoError(t, err)
+	require.Len(t, found.GetGrants(), 2)
+	_, err = fileClient.UnshareFile(ownerCtx, &pb.UnshareFileRequest{FileId: id, User: &pb.Owner{Name: "dave"}})
+	require.NoError(t, err)
+
+	rename := &pb.MoveRequest{FileId: id, Title: "final.txt"}
+	_, err = fileClient.Move(readerCtx, rename)
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	// sharing the file again changes the permission
+	updated, err := share(ownerCtx, reader.Username, pb.Permission_WRITE)
+	require.NoError(t, err)
+	require.Len(t, updated.GetFile().GetGrants(), 1)
+	require.Equal(t, pb.Permission_WRITE, updated.GetFile().GetGrants()[0].GetPermission())
+	_, err = fileClient.Move(readerCtx, rename)
+	require.NoError(t, err)
+
+	// the grants are stored with the file
+	reloaded, err := service.NewDiskFileStore(dir)
+	require.NoError(t, err)
+	require.Equal(t, reader.Username, reloaded.Find(id).GetGrants()[0].GetUser().GetName())
+
+	unshare := &pb.UnshareFileRequest{FileId: id, User: &pb.Owner{Name: reader.Username}}
+	_, err = fileClient.UnshareFile(readerCtx, unshare)
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+	_, err = fileClient.UnshareFile(ownerCtx, unshare)
+	require.NoError(t, err)
+	_, err = download(readerCtx)
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+	require.Empty(t, shared(readerCtx, reader))
+
+	_, err = fileClient.UnshareFile(ownerCtx, unshare)
+	require.Equal(t, codes.NotFound, status.Code(err))
+}
@@ -0,0 +1,95 @@
+package service_test
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Nextasy01/grpc-file-service/pb"
+	"github.com/Nextasy01/grpc-file-service/service"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestTrash(t *testing.T) {
+	t.Parallel()
+
+	fileStore, err := service.NewDiskFileStore(t.TempDir())
+	require.NoError(t, err)
+	t.Cleanup(func() { fileStore.Close() })
+
+	jwtManager := service.NewJWTManager("test-secret", time.Minute)
+	serverAddress := startTestAuthFileServer(t, fileStore, jwtManager)
+	fileClient := newTestFileClient(t, serverAddress)
+
+	owner := createUser(t, service.NewInMemoryUserStore(), "owner", "secret", "user")
+	ctx := authContext(t, jwtManager, owner)
+	user := &pb.Owner{Name: owner.Username}
+
+	file := &pb.File{Title: "notes.txt", Owner: user}
+	require.NoError(t, fileStore.Save(file, strings.NewReader("notes")))
+
+	res, err := fileClient.Delete(ctx, &pb.DeleteFileRequest{FileId: file.GetId()})
+	require.NoError(t, err)
+	require.NotNil(t, res.GetFile().GetDeletedAt())
+	require.Nil(t, fileStore.Find(file.GetId()))
+	require.EqualValues(t, 5, fileStore.Usage(owner.Username).Bytes) // trashed files still use storage
+
+	stream, err := fileClient.ListTrash(ctx, &pb.ListTrashRequest{User: user})
+	require.NoError(t, err)
+	trashed, err := stream.Recv()
+	require.NoError(t, err)
+	require.Equal(t, file.GetId(), trashed.GetFile().GetId())
+	_, err = stream.Recv()
+	require.Equal(t, io.EOF, err)
+
+	restored, err := fileClient.RestoreFromTrash(ctx, &pb.RestoreFromTrashRequest{FileId: file.GetId()})
+	require.NoError(t, err)
+	require.Nil(t, restored.GetFile().GetDeletedAt())
+	require.NotNil(t, fileStore.Find(file.GetId()))
+
+	_, err = fileClient.RestoreFromTrash(ctx, &pb.RestoreFromTrashRequest{FileId: file.GetId()})
+	require.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = fileClient.Delete(ctx, &pb.DeleteFileRequest{FileId: file.GetId()})
+	require.NoError(t, err)
+	emptied, err := fileClient.EmptyTrash(ctx, &pb.EmptyTrashRequest{User: user})
+	require.NoError(t, err)
+	require.EqualValues(t, 1, emptied.GetRemoved())
+	require.Zero(t, fileStore.Usage(owner.Username).Bytes)
+}
+
+func TestTrashPurger(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	fileStore, err := service.NewDiskFileStore(dir)
+	require.NoError(t, err)
+
+	old := &pb.File{Title: "old.txt", Owner: &pb.Owner{Name: "testUser"}}
+	require.NoError(t, fileStore.Save(old, strings.NewReader("old")))
+	_, err = fileStore.Trash(old.GetId())
+	require.NoError(t, err)
+
+	// the trash survives a restart
+	require.NoError(t, fileStore.Close())
+	fileStore, err = service.NewDiskFileStore(dir)
+	require.NoError(t, err)
+	defer fileStore.Close()
+	require.NotNil(t, fileStore.FindTrashed(old.GetId()))
+
+	time.Sleep(20 * time.Millisecond)
+	recent := &pb.File{Title: "recent.txt", Owner: &pb.Owner{Name: "testUser"}}
+	require.NoError(t, fileStore.Save(recent, strings.NewReader("recent")))
+	_, err = fileStore.Trash(recent.GetId())
+	require.NoError(t, err)
+
+	purged, err := service.NewTrashPurger(fileStore, 10*time.Millisecond).Purge()
+	require.NoError(t, err)
+	require.Equal(t, 1, purged)
+	require.Nil(t, fileStore.FindTrashed(old.GetId()))
+	require.NotNil(t, fileStore.FindTrashed(recent.GetId()))
+	require.Len(t, blobFiles(t, dir), 1)
+}
@@ -0,0 +1,160 @@
+package service_test
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Nextasy01/grpc-file-service/pb"
+	"github.com/Nextasy01/grpc-file-service/service"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestResumableUpload(t *testing.T) {
+	t.Parallel()
+
+	fileStore := service.NewInMemoryFileStore(t.TempDir())
+	jwtManager := service.NewJWTManager("test-secret", time.Minute)
+	serverAddress := startTestAuthFileServer(t, fileStore, jwtManager)
+	fileClient := newTestFileClient(t, serverAddress)
+
+	user := createUser(t, service.NewInMemoryUserStore(), "uploader", "secret", "user")
+	ctx := authContext(t, jwtManager, user)
+
+	session, err := fileClient.CreateUploadSession(ctx, &pb.CreateUploadSessionRequest{
+		File: &pb.File{Title: "data.csv", Size: 10, Owner: &pb.Owner{Name: user.Username}},
+	})
+	require.NoError(t, err)
+	require.Zero(t, session.GetOffset())
+
+	// the first attempt is interrupted after 4 bytes
+	res := resumeUpload(t, ctx, fileClient, session.GetSessionId(), 0, "a,b,")
+	require.EqualValues(t, 4, res.GetSession().GetOffset())
+	require.Nil(t, res.GetFile())
+
+	session, err = fileClient.GetUploadSession(ctx, &pb.GetUploadSessionRequest{SessionId: session.GetSessionId()})
+	require.NoError(t, err)
+	require.EqualValues(t, 4, session.GetOffset())
+
+	stream, err := fileClient.ResumeUpload(ctx)
+	require.NoError(t, err)
+	require.NoError(t, stream.Send(&pb.ResumeUploadRequest{SessionId: session.GetSessionId(), Offset: 0}))
+	_, err = stream.CloseAndRecv()
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	res = resumeUpload(t, ctx, fileClient, session.GetSessionId(), 4, "c,d,e\n")
+	require.NotNil(t, res.GetFile())
+	require.EqualValues(t, 10, res.GetFile().GetSize())
+
+	contents, err := fileStore.Open(res.GetFile().GetId())
+	require.NoError(t, err)
+	defer contents.Close()
+	data, err := io.ReadAll(contents)
+	require.NoError(t, err)
+	require.Equal(t, "a,b,c,d,e\n", string(data))
+
+	_, err = fileClient.GetUploadSession(ctx, &pb.GetUploadSessionRequest{SessionId: session.GetSessionId()})
+	require.Equal(t, codes.NotFound, status.Code(err)) // finished sessions are removed
+}
+
+func TestUploadSessionExpiry(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	fileStore := service.NewInMemoryFileStore(dir)
+	sessions := service.NewUploadSessionStore(fileStore, 10*time.Millisecond)
+	t.Cleanup(sessions.Close)
+
+	file := &pb.File{Title: "abandoned.bin", Size: 100}
+	writer, err := fileStore.Create(file)
+	require.NoError(t, err)
+	_, err = writer.Write([]byte("partial"))
+	require.NoError(t, err)
+
+	session, err := sessions.Create(file, writer)
+	require.NoError(t, err)
+	time.Sleep(20 * time.Millisecond)
+	sessions.RemoveExpired()
+
+	require.Nil(t, sessions.Find(session.ID))
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Empty(t, entries)
+}
+
+func TestUploadSessionRestart(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	fileStore, err := service.NewDiskFileStore(dir)
+	require.NoError(t, err)
+
+	jwtManager := service.NewJWTManager("test-secret", time.Minute)
+	user := createUser(t, service.NewInMemoryUserStore(), "uploader", "secret", "user")
+	ctx := authContext(t, jwtManager, user)
+	startServer := func(fileStore service.FileStore) pb.FileServiceClient {
+		fileServer := newTestFileServer(t, fileStore)
+		return newTestFileClient(t, serveTestFileServer(t, fileServer, testAuthServerOptions(jwtManager)...))
+	}
+	fileClient := startServer(fileStore)
+
+	session, err := fileClient.CreateUploadSession(ctx, &pb.CreateUploadSessionRequest{
+		File: &pb.File{Title: "data.csv", Size: 10, Owner: &pb.Owner{Name: user.Username}, Checksum: checksumOf("a,b,c,d,e\n")},
+	})
+	require.NoError(t, err)
+	resumeUpload(t, ctx, fileClient, session.GetSessionId(), 0, "a,b,")
+
+	// data after the offset, as if the server crashed before syncing it, isn't part of the upload
+	uploads, err := filepath.Glob(filepath.Join(dir, ".upload-*"))
+	require.NoError(t, err)
+	require.Len(t, uploads, 1)
+	temp, err := os.OpenFile(uploads[0], os.O_APPEND|os.O_WRONLY, 0)
+	require.NoError(t, err)
+	_, err = temp.WriteString("lost")
+	require.NoError(t, err)
+	require.NoError(t, temp.Close())
+
+	// the session is continued after a restart
+	require.NoError(t, fileStore.Close())
+	fileStore, err = service.NewDiskFileStore(dir)
+	require.NoError(t, err)
+	defer fileStore.Close()
+	fileClient = startServer(fileStore)
+
+	found, err := fileClient.GetUploadSession(ctx, &pb.GetUploadSessionRequest{SessionId: session.GetSessionId()})
+	require.NoError(t, err)
+	require.EqualValues(t, 4, found.GetOffset())
+
+	res := resumeUpload(t, ctx, fileClient, session.GetSessionId(), 4, "c,d,e\n")
+	require.NotNil(t, res.GetFile())
+
+	contents, err := fileStore.Open(res.GetFile().GetId())
+	require.NoError(t, err)
+	defer contents.Close()
+	data, err := io.ReadAll(contents)
+	require.NoError(t, err)
+	require.Equal(t, "a,b,c,d,e\n", string(data))
+
+	// finished sessions aren't continued after the next restart
+	require.NoError(t, fileStore.Close())
+	fileStore, err = service.NewDiskFileStore(dir)
+	require.NoError(t, err)
+	require.Empty(t, fileStore.Uploads())
+}
+
+func resumeUpload(t *testing.T, ctx context.Context, fileClient pb.FileServiceClient, sessionId string, offset uint64, data string) *pb.ResumeUploadResponse {
+	stream, err := fileClient.ResumeUpload(ctx)
+	require.NoError(t, err)
+
+	require.NoError(t, stream.Send(&pb.ResumeUploadRequest{SessionId: sessionId, Offset: offset}))
+	require.NoError(t, stream.Send(&pb.ResumeUploadRequest{Chunk: []byte(data)}))
+
+	res, err := stream.CloseAndRecv()
+	require.NoError(t, err)
+	return res
+}
@@ -0,0 +1,87 @@
+package service_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Nextasy01/grpc-file-service/pb"
+	"github.com/Nextasy01/grpc-file-service/service"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestVersioning(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	fileStore, err := service.NewDiskFileStore(dir)
+	require.NoError(t, err)
+
+	jwtManager := service.NewJWTManager("test-secret", time.Minute)
+	fileServer := newTestFileServer(t, fileStore)
+	fileServer.EnableVersioning()
+	fileClient := newTestFileClient(t, serveTestFileServer(t, fileServer, testAuthServerOptions(jwtManager)...))
+
+	user := createUser(t, service.NewInMemoryUserStore(), "writer", "secret", "user")
+	ctx := authContext(t, jwtManager, user)
+	owner := &pb.Owner{Name: user.Username}
+
+	first, err := uploadFile(ctx, fileClient, owner, "notes.txt", "one")
+	require.NoError(t, err)
+	require.EqualValues(t, 1, first.GetFile().GetVersion())
+
+	// the same owner and title makes a new version of the file
+	second, err := uploadFile(ctx, fileClient, owner, "notes.txt", "two")
+	require.NoError(t, err)
+	require.Equal(t, first.GetFile().GetId(), second.GetFile().GetId())
+	require.EqualValues(t, 2, second.GetFile().GetVersion())
+	id := second.GetFile().GetId()
+
+	versions, err := fileClient.ListVersions(ctx, &pb.ListVersionsRequest{FileId: id})
+	require.NoError(t, err)
+	require.Len(t, versions.GetVersions(), 2)
+
+	stream, err := fileClient.Download(ctx, &pb.DownloadFileRequest{FileId: id, Version: 1})
+	require.NoError(t, err)
+	data, err := receiveDownload(stream)
+	require.NoError(t, err)
+	require.Equal(t, "one", data)
+
+	restored, err := fileClient.RestoreVersion(ctx, &pb.RestoreVersionRequest{FileId: id, Version: 1})
+	require.NoError(t, err)
+	require.EqualValues(t, 3, restored.GetFile().GetVersion())
+
+	stream, err = fileClient.Download(ctx, &pb.DownloadFileRequest{FileId: id})
+	require.NoError(t, err)
+	data, err = receiveDownload(stream)
+	require.NoError(t, err)
+	require.Equal(t, "one", data)
+
+	pruned, err := fileClient.PruneVersions(ctx, &pb.PruneVersionsRequest{FileId: id, Keep: 1})
+	require.NoError(t, err)
+	require.EqualValues(t, 1, pruned.GetRemoved())
+
+	stream, err = fileClient.Download(ctx, &pb.DownloadFileRequest{FileId: id, Version: 1})
+	require.NoError(t, err)
+	_, err = receiveDownload(stream)
+	require.Equal(t, codes.NotFound, status.Code(err))
+
+	// the history survives a restart
+	require.NoError(t, fileStore.Close())
+	fileStore, err = service.NewDiskFileStore(dir)
+	require.NoError(t, err)
+	defer fileStore.Close()
+
+	history := fileStore.Versions(id)
+	require.Len(t, history, 2)
+	require.EqualValues(t, 2, history[0].GetVersion())
+	require.EqualValues(t, 3, history[1].GetVersion())
+	require.Equal(t, service.Usage{Bytes: 6, Files: 1}, fileStore.Usage(user.Username))
+	require.Len(t, blobFiles(t, dir), 2) // versions 1 and 3 share a blob
+
+	_, err = fileStore.Delete(id)
+	require.NoError(t, err)
+	require.Empty(t, blobFiles(t, dir))
+	require.Equal(t, service.Usage{}, fileStore.Usage(user.Username))
+}
@@ -0,0 +1,119 @@
+package service_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Nextasy01/grpc-file-service/pb"
+	"github.com/Nextasy01/grpc-file-service/service"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestWatch(t *testing.T) {
+	t.Parallel()
+
+	fileStore := service.NewInMemoryFileStore(t.TempDir())
+	jwtManager := service.NewJWTManager("test-secret", time.Minute)
+	fileClient := newTestFileClient(t, startTestAuthFileServer(t, fileStore, jwtManager))
+	userStore := service.NewInMemoryUserStore()
+	user := createUser(t, userStore, "watcher", "secret", "user")
+	owner := &pb.Owner{Name: user.Username}
+
+	ctx, cancel := context.WithTimeout(authContext(t, jwtManager, user), 5*time.Second)
+	defer cancel()
+
+	start := fileStore.LastEvent()
+	live, err := fileClient.Watch(ctx, &pb.WatchRequest{Owner: owner, AfterSequence: start})
+	require.NoError(t, err)
+
+	file := &pb.File{Title: "a.log", Path: "ci", Owner: owner}
+	require.NoError(t, fileStore.Save(file, strings.NewReader("a")))
+	require.NoError(t, fileStore.Save(&pb.File{Title: "b.txt", Owner: owner}, strings.NewReader("b")))
+	require.NoError(t, fileStore.Save(&pb.File{Title: "c.txt", Owner: &pb.Owner{Name: "other"}}, strings.NewReader("c")))
+	_, err = fileStore.UpdateMetadata(file.GetId(), func(file *pb.File) error {
+		file.Labels = map[string]string{"env": "prod"}
+		return nil
+	})
+	require.NoError(t, err)
+	_, err = fileStore.MoveFolder(owner.GetName(), "ci", "archive/ci")
+	require.NoError(t, err)
+	_, err = fileStore.Trash(file.GetId())
+	require.NoError(t, err)
+
+	events := receiveEvents(t, live, 5)
+	require.Equal(t, []string{"CREATED ci/a.log", "CREATED b.txt", "UPDATED ci/a.log", "MOVED archive/ci/a.log", "DELETED archive/ci/a.log"},
+		describeEvents(events))
+	require.Equal(t, "ci/a.log", events[3].GetPreviousPath())
+	for i := 1; i < len(events); i++ {
+		require.Greater(t, events[i].GetSequence(), events[i-1].GetSequence())
+	}
+
+	// a reconnecting client resumes after the last event it saw
+	resumed, err := fileClient.Watch(ctx, &pb.WatchRequest{Owner: owner, AfterSequence: events[1].GetSequence()})
+	require.NoError(t, err)
+	for i, event := range receiveEvents(t, resumed, 3) {
+		require.Equal(t, events[i+2].GetSequence(), event.GetSequence())
+	}
+
+	// moves out of the folder are sent as well
+	inFolder, err := fileClient.Watch(ctx, &pb.WatchRequest{Owner: owner, Folder: "ci", AfterSequence: start})
+	require.NoError(t, err)
+	require.Equal(t, []string{"CREATED ci/a.log", "UPDATED ci/a.log", "MOVED archive/ci/a.log"}, describeEvents(receiveEvents(t, inFolder, 3)))
+
+	labeled, err := fileClient.Watch(ctx, &pb.WatchRequest{Owner: owner, Labels: map[string]string{"env": "prod"}, AfterSequence: start})
+	require.NoError(t, err)
+	require.Equal(t, []string{"UPDATED ci/a.log", "MOVED archive/ci/a.log", "DELETED archive/ci/a.log"}, describeEvents(receiveEvents(t, labeled, 3)))
+
+	// files shared with the user are watched as well, without the other users they are shared with
+	shared := &pb.File{Title: "shared.txt", Owner: &pb.Owner{Name: "other"}}
+	require.NoError(t, fileStore.Save(shared, strings.NewReader("d")))
+	after := fileStore.LastEvent()
+	_, err = fileStore.UpdateMetadata(shared.GetId(), func(file *pb.File) error {
+		file.Grants = []*pb.Grant{
+			{User: &pb.Owner{Name: owner.GetName()}, Permission: pb.Permission_READ},
+			{User: &pb.Owner{Name: "third"}, Permission: pb.Permission_READ},
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	sharedEvents, err := fileClient.Watch(ctx, &pb.WatchRequest{Owner: owner, AfterSequence: start})
+	require.NoError(t, err)
+	events = receiveEvents(t, sharedEvents, 6)
+	require.Equal(t, "UPDATED shared.txt", describeEvents(events)[5])
+	require.Greater(t, events[5].GetSequence(), after)
+	require.Empty(t, events[5].GetFile().GetGrants())
+
+	for code, req := range map[codes.Code]*pb.WatchRequest{
+		codes.OutOfRange:       {Owner: owner, AfterSequence: 1},
+		codes.InvalidArgument:  {Owner: owner, AfterSequence: fileStore.LastEvent() + 1},
+		codes.PermissionDenied: {Owner: &pb.Owner{Name: "other"}},
+	} {
+		stream, err := fileClient.Watch(ctx, req)
+		require.NoError(t, err)
+		_, err = stream.Recv()
+		require.Equal(t, code, status.Code(err))
+	}
+}
+
+func receiveEvents(t *testing.T, stream pb.FileService_WatchClient, n int) []*pb.FileEvent {
+	events := make([]*pb.FileEvent, 0, n)
+	for len(events) < n {
+		event, err := stream.Recv()
+		require.NoError(t, err)
+		events = append(events, event)
+	}
+	return events
+}
+
+func describeEvents(events []*pb.FileEvent) []string {
+	descriptions := make([]string, 0, len(events))
+	for _, event := range events {
+		file := event.GetFile()
+		descriptions = append(descriptions, event.GetType().String()+" "+strings.TrimPrefix(file.GetPath()+"/"+file.GetTitle(), "/"))
+	}
+	return descriptions
+}